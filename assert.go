@@ -0,0 +1,31 @@
+package testkit
+
+import "testing"
+
+// AssertRowCount checks that exactly expected rows of the table match where, an empty where counts every row
+// A mismatch fails the test and lets it continue, a failing query stops it
+func (fm *FixtureManager) AssertRowCount(t testing.TB, table string, where map[string]any, expected int) {
+	t.Helper()
+
+	count, err := fm.CountRows(t.Context(), table, where)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if count != expected {
+		t.Errorf("expected %d rows in table %s matching %v, got %d", expected, table, where, count)
+	}
+}
+
+// AssertRowExists checks that at least one row of the table matches where
+// A missing row fails the test and lets it continue, a failing query stops it
+func (fm *FixtureManager) AssertRowExists(t testing.TB, table string, where map[string]any) {
+	t.Helper()
+
+	count, err := fm.CountRows(t.Context(), table, where)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if count == 0 {
+		t.Errorf("expected a row in table %s matching %v, found none", table, where)
+	}
+}
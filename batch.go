@@ -0,0 +1,245 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxBatchParameters is the PostgreSQL limit of bind parameters per statement, batches are split before it
+const maxBatchParameters = 65535
+
+// preparedRow is a fixture row ready to be inserted, with its columns in name order
+type preparedRow struct {
+	index   int
+	alias   string
+	record  map[string]any
+	columns []string
+	values  []preparedValue
+}
+
+// preparedValue is the value of one column, either bound with an optional cast or inlined as an SQL expression
+type preparedValue struct {
+	expression string
+	value      any
+	cast       string
+}
+
+// params returns the number of bind parameters of the row
+func (r preparedRow) params() int {
+	n := 0
+	for _, v := range r.values {
+		if v.expression == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// prepareRow converts the values of a resolved record for binding
+// Introspected column types take precedence over the hints of ConfigureColumnTypes
+func (fm *FixtureManager) prepareRow(
+	index int, alias string, record map[string]any, types map[string]columnType, hints map[string]string,
+) (preparedRow, error) {
+	row := preparedRow{index: index, alias: alias, record: record}
+	for column := range record {
+		row.columns = append(row.columns, column)
+	}
+	sort.Strings(row.columns)
+
+	row.values = make([]preparedValue, 0, len(row.columns))
+	for _, column := range row.columns {
+		value := record[column]
+
+		if v, ok := value.(string); ok && fm.config.NullSentinel != "" && v == fm.config.NullSentinel {
+			value = nil
+		}
+
+		// SQL expressions are inlined instead of bound
+		if expression, ok := value.(sqlExpression); ok {
+			row.values = append(row.values, preparedValue{expression: string(expression)})
+			continue
+		}
+
+		var cast string
+		var err error
+		if ct, ok := types[column]; ok {
+			value, cast, err = bindTyped(ct, value)
+		} else {
+			value, err = fm.bindStructured(hints[column], value)
+		}
+		if err != nil {
+			return preparedRow{}, fmt.Errorf("failed to bind column %s: %w", column, err)
+		}
+		row.values = append(row.values, preparedValue{value: value, cast: cast})
+	}
+	return row, nil
+}
+
+// insertBatch inserts rows sharing the same columns with a single INSERT and tracks their primary keys
+// It returns the number of inserted rows
+func (fm *FixtureManager) insertBatch(
+	ctx context.Context, tx *sql.Tx, source, tableName string, rows []preparedRow, pending map[string][]trackedRecord,
+	load *loadState,
+) (int64, error) {
+	tuples := make([]string, 0, len(rows))
+	var values []any
+	param := 1
+	for _, row := range rows {
+		placeholders := make([]string, len(row.values))
+		for i, v := range row.values {
+			if v.expression != "" {
+				placeholders[i] = v.expression
+				continue
+			}
+			placeholders[i] = fm.config.Dialect.Placeholder(param)
+			if v.cast != "" {
+				placeholders[i] += "::" + v.cast
+			}
+			values = append(values, v.value)
+			param++
+		}
+		tuples = append(tuples, "("+strings.Join(placeholders, ", ")+")")
+	}
+
+	// Build and execute query
+	// This is safe because we're using quoted identifiers and parameterized values
+	dialect := fm.config.Dialect
+	//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES %s",
+		dialect.QuoteIdentifier(tableName),
+		strings.Join(dialect.quoteIdentifiers(rows[0].columns), ", "),
+		strings.Join(tuples, ", "),
+	)
+	query += fm.conflictClause(tableName, rows[0].columns)
+
+	primaryKeys := fm.getPrimaryKeys(tableName)
+	var returning string
+	if dialect == DialectPostgres && !fm.config.DryRun {
+		var err error
+		if returning, err = fm.returningColumns(ctx, tx, tableName, primaryKeys, load); err != nil {
+			return 0, err
+		}
+	}
+
+	var affected int64
+	var keys []trackedRecord
+	switch {
+	case fm.config.DryRun:
+		// Nothing is inserted, so only the keys given by the fixture can be tracked for the logged cleanup
+		logDryRun(query, values)
+		affected = int64(len(rows))
+		keys = fixtureKeys(source, rows, primaryKeys)
+	case returning != "":
+		// RETURNING captures generated keys, a row skipped by a conflict clause returns nothing
+		_, returned, err := queryRows(ctx, tx, query+" RETURNING "+returning, values...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert %s: %w", describeRows(rows), err)
+		}
+		for i, returnedRow := range returned {
+			pkValues := make(map[string]any, len(primaryKeys))
+			for _, pk := range primaryKeys {
+				pkValues[pk] = returnedRow[pk]
+			}
+			// Rows come back in VALUES order, only skipped rows make the position unknown
+			index := rows[0].index
+			if len(returned) == len(rows) {
+				index = rows[i].index
+			}
+			keys = append(keys, trackedRecord{Keys: pkValues, Source: source, Index: index})
+			if load.onInsert != nil {
+				load.inserted(tableName, returnedRow)
+			}
+		}
+		// Generated values become available to alias references, aliased rows are always alone in their batch
+		if rows[0].alias != "" && len(returned) == 1 {
+			rows[0].record = copyRecord(rows[0].record)
+			for column, value := range returned[0] {
+				rows[0].record[column] = value
+			}
+		}
+		affected = int64(len(returned))
+	default:
+		result, err := tx.ExecContext(ctx, query, values...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert %s: %w", describeRows(rows), err)
+		}
+		if affected, err = result.RowsAffected(); err != nil {
+			return 0, fmt.Errorf("failed to check affected rows: %w", err)
+		}
+		// Without RETURNING only the keys given by the fixture are known, and only the affected row count tells
+		// whether rows were skipped, so a batch of which ConflictDoNothing skipped any row is not tracked at all
+		if fm.config.ConflictMode != ConflictDoNothing || affected >= int64(len(rows)) {
+			keys = fixtureKeys(source, rows, primaryKeys)
+		}
+	}
+
+	// Store primary key values for cleanup, unless the table is cleaned up in bulk
+	if !fm.isLargeTable(tableName) {
+		for _, key := range keys {
+			if len(key.Keys) > 0 {
+				pending[tableName] = append(pending[tableName], key)
+			}
+		}
+	}
+	if fm.config.StrictInsert && affected != int64(len(rows)) {
+		return 0, fmt.Errorf("%s of table %s from %s: only %d of %d rows were inserted",
+			describeRows(rows), tableName, source, affected, len(rows))
+	}
+
+	if alias := rows[0].alias; alias != "" {
+		if err := load.aliases.add(tableName, alias, rows[0].record); err != nil {
+			return 0, fmt.Errorf("row %d: %w", rows[0].index, err)
+		}
+	}
+	return affected, nil
+}
+
+// returningColumns returns the RETURNING list of an INSERT into the table, empty when the keys cannot be returned
+// Configured primary keys are trusted, while the default id is only returned when the table has such a column,
+// so tables without one, e.g. join tables, are inserted without RETURNING and only their fixture keys are tracked
+func (fm *FixtureManager) returningColumns(
+	ctx context.Context, q querier, tableName string, primaryKeys []string, load *loadState,
+) (string, error) {
+	if load.onInsert != nil {
+		return "*", nil
+	}
+	if len(fm.tableConfigs[tableName].PrimaryKeys) == 0 {
+		types, err := fm.getColumnTypes(ctx, q, tableName)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := types["id"]; !ok {
+			return "", nil
+		}
+	}
+	return strings.Join(fm.config.Dialect.quoteIdentifiers(primaryKeys), ", "), nil
+}
+
+// fixtureKeys returns the primary key values written in the fixture rows, leaving out SQL expressions
+func fixtureKeys(source string, rows []preparedRow, primaryKeys []string) []trackedRecord {
+	keys := make([]trackedRecord, 0, len(rows))
+	for _, row := range rows {
+		pkValues := make(map[string]any, len(primaryKeys))
+		for _, pk := range primaryKeys {
+			if value, exists := row.record[pk]; exists {
+				if _, isExpression := value.(sqlExpression); !isExpression {
+					pkValues[pk] = value
+				}
+			}
+		}
+		keys = append(keys, trackedRecord{Keys: pkValues, Source: source, Index: row.index})
+	}
+	return keys
+}
+
+// describeRows names the fixture rows of a batch for error messages
+func describeRows(rows []preparedRow) string {
+	if len(rows) == 1 {
+		return fmt.Sprintf("row %d", rows[0].index)
+	}
+	return fmt.Sprintf("rows %d-%d", rows[0].index, rows[len(rows)-1].index)
+}
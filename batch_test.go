@@ -0,0 +1,328 @@
+package testkit
+
+import (
+	"database/sql"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestInsertReturnsKeysOfTablesWithIDColumn(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+		switch {
+		case isColumnQuery(query):
+			return columnRows(map[string]string{"id": "integer", "name": "text"}), nil
+		case strings.HasPrefix(query, "INSERT"):
+			return &fakeRows{columns: []string{"id"}, rows: [][]any{{int64(41)}}}, nil
+		}
+		return nil, nil
+	}
+
+	fixture := writeFixture(t, "users.yml", "users:\n  - name: alice\n")
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queryTexts("INSERT")
+	want := `INSERT INTO "users" ("name") VALUES ($1) RETURNING "id"`
+	if len(inserts) != 1 || inserts[0] != want {
+		t.Fatalf("inserts = %q, want %q", inserts, want)
+	}
+	keys := fm.GetInsertedKeys("users")
+	if len(keys) != 1 || keys[0]["id"] != int64(41) {
+		t.Errorf("GetInsertedKeys() = %v, want the generated id 41", keys)
+	}
+}
+
+func TestInsertWithoutIDColumnSkipsReturning(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+		if isColumnQuery(query) {
+			return columnRows(map[string]string{"user_id": "integer", "group_id": "integer"}), nil
+		}
+		t.Errorf("unexpected query %q", query)
+		return nil, nil
+	}
+
+	fixture := writeFixture(t, "memberships.yml", "user_groups:\n  - user_id: 1\n    group_id: 2\n")
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queryTexts("INSERT")
+	want := `INSERT INTO "user_groups" ("group_id", "user_id") VALUES ($1, $2)`
+	if len(inserts) != 1 || inserts[0] != want {
+		t.Fatalf("inserts = %q, want %q", inserts, want)
+	}
+	if keys := fm.GetInsertedKeys("user_groups"); keys != nil {
+		t.Errorf("GetInsertedKeys() = %v, want no tracked keys", keys)
+	}
+	if tables := fm.LoadedTables(); len(tables) != 1 || tables[0] != "user_groups" {
+		t.Errorf("LoadedTables() = %v, want [user_groups]", tables)
+	}
+}
+
+func TestInsertTrustsConfiguredPrimaryKeys(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fm.ConfigureTable("user_groups", []string{"user_id", "group_id"})
+	fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+		if isColumnQuery(query) {
+			t.Errorf("configured primary keys should not need the column catalog")
+		}
+		return &fakeRows{columns: []string{"user_id", "group_id"}, rows: [][]any{{int64(1), int64(2)}}}, nil
+	}
+
+	fixture := writeFixture(t, "memberships.yml", "user_groups:\n  - user_id: 1\n    group_id: 2\n")
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queryTexts("INSERT")
+	if len(inserts) != 1 || !strings.HasSuffix(inserts[0], ` RETURNING "user_id", "group_id"`) {
+		t.Fatalf("inserts = %q, want the configured keys returned", inserts)
+	}
+	if keys := fm.GetInsertedKeys("user_groups"); len(keys) != 1 {
+		t.Errorf("GetInsertedKeys() = %v, want one tracked row", keys)
+	}
+}
+
+func TestLoadTableWithoutIDColumn(t *testing.T) {
+	db := newPostgresDB(t,
+		"CREATE TABLE users (id serial PRIMARY KEY, name text NOT NULL)",
+		"CREATE TABLE tags (name text PRIMARY KEY)",
+		"CREATE TABLE user_tags (user_id int NOT NULL REFERENCES users, tag text NOT NULL REFERENCES tags)",
+	)
+	fm := NewFixtureManager(db)
+	fm.ConfigureTable("tags", []string{"name"})
+	fm.ConfigureTableDependencies("user_tags", []string{"users", "tags"})
+
+	fixture := writeFixture(t, "tags.yml", `
+users:
+  - _alias: alice
+    name: alice
+tags:
+  - name: admin
+user_tags:
+  - user_id: $users.alice.id
+    tag: admin
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	if count := countTableRows(t, db, "user_tags"); count != 1 {
+		t.Fatalf("user_tags has %d rows, want 1", count)
+	}
+
+	// The join table is not tracked, remove its row before the rows it references
+	if _, err := db.Exec("DELETE FROM user_tags"); err != nil {
+		t.Fatalf("failed to empty user_tags: %v", err)
+	}
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+	for _, table := range []string{"users", "tags"} {
+		if count := countTableRows(t, db, table); count != 0 {
+			t.Errorf("%s has %d rows after cleanup, want 0", table, count)
+		}
+	}
+}
+
+func TestStrictInsertFlagsRowsSkippedByConflicts(t *testing.T) {
+	const fixture = `
+users:
+  - id: 1
+    name: alice
+  - id: 2
+    name: bob
+`
+	// Row 1, bob, is the skipped row
+	skipped := []string{"row 1 of table users from ", "users.yml: only 0 of 1 rows were inserted"}
+	tests := []struct {
+		name    string
+		strict  bool
+		idTable bool
+		wantErr []string
+	}{
+		{name: "returning not strict", idTable: true},
+		{name: "returning strict", strict: true, idTable: true, wantErr: skipped},
+		{name: "exec not strict"},
+		{name: "exec strict", strict: true, wantErr: skipped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.ConflictMode = ConflictDoNothing
+			config.StrictInsert = tt.strict
+			fm, fake := newFakeManager(t, config)
+			// bob already exists, so the conflict clause skips his row
+			conflicts := func(args []any) bool { return len(args) > 0 && args[0] == 2 }
+			fake.onQuery = func(query string, args []any) (*fakeRows, error) {
+				if isColumnQuery(query) {
+					if tt.idTable {
+						return columnRows(map[string]string{"id": "integer", "name": "text"}), nil
+					}
+					return columnRows(map[string]string{"user_id": "integer"}), nil
+				}
+				if conflicts(args) {
+					return &fakeRows{columns: []string{"id"}}, nil
+				}
+				return &fakeRows{columns: []string{"id"}, rows: [][]any{{args[0]}}}, nil
+			}
+			fake.onExec = func(_ string, args []any) (int64, error) {
+				if conflicts(args) {
+					return 0, nil
+				}
+				return 1, nil
+			}
+
+			err := fm.LoadYAMLFixtures(writeFixture(t, "users.yml", fixture))
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatal("LoadYAMLFixtures() error = nil, want the skipped row flagged")
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("LoadYAMLFixtures() error = %v, want it to contain %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadYAMLFixtures() error = %v", err)
+			}
+			if keys := fm.GetInsertedKeys("users"); tt.idTable && (len(keys) != 1 || keys[0]["id"] != 1) {
+				t.Errorf("GetInsertedKeys() = %v, want only the inserted row tracked", keys)
+			}
+			if inserts := fake.queryTexts("INSERT"); len(inserts) != 2 || !strings.Contains(inserts[1], "ON CONFLICT") {
+				t.Errorf("inserts = %q, want both rows inserted with the conflict clause", inserts)
+			}
+		})
+	}
+}
+
+func TestConflictDoNothingWithoutReturningTracksOnlyInsertedRows(t *testing.T) {
+	const fixture = `
+users:
+  - id: 1
+    name: alice
+  - id: 2
+    name: bob
+`
+	tests := []struct {
+		name      string
+		batchSize int
+		want      []any
+	}{
+		{name: "row by row", want: []any{1}},
+		{name: "batch with a skipped row", batchSize: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.Dialect = DialectMySQL
+			config.ConflictMode = ConflictDoNothing
+			config.BatchSize = tt.batchSize
+			fm, fake := newFakeManager(t, config)
+			// bob already exists, so the conflict clause skips his row
+			fake.onExec = func(_ string, args []any) (int64, error) {
+				var affected int64
+				for i := 0; i < len(args); i += 2 {
+					if args[i] != 2 {
+						affected++
+					}
+				}
+				return affected, nil
+			}
+
+			if err := fm.LoadYAMLFixtures(writeFixture(t, "users.yml", fixture)); err != nil {
+				t.Fatalf("LoadYAMLFixtures() error = %v", err)
+			}
+
+			var got []any
+			for _, key := range fm.GetInsertedKeys("users") {
+				got = append(got, key["id"])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("tracked ids = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNullSentinel(t *testing.T) {
+	const fixture = `
+users:
+  - bio: <null> or not
+    name: <null>
+    nickname: \N
+    tags: [<null>]
+`
+	tests := []struct {
+		name     string
+		sentinel string
+		want     []any
+	}{
+		{name: "disabled", want: []any{"<null> or not", "<null>", `\N`}},
+		{name: "custom sentinel", sentinel: "<null>", want: []any{"<null> or not", nil, `\N`}},
+		{name: "backslash N", sentinel: `\N`, want: []any{"<null> or not", "<null>", nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.NullSentinel = tt.sentinel
+			fm, fake := newFakeManager(t, config)
+			fake.returnIDs()
+
+			if err := fm.LoadYAMLFixtures(writeFixture(t, "users.yml", fixture)); err != nil {
+				t.Fatalf("LoadYAMLFixtures() error = %v", err)
+			}
+			inserts := fake.queries("INSERT")
+			if len(inserts) != 1 || len(inserts[0].Args) != 4 {
+				t.Fatalf("inserts = %v, want one row of 4 columns", inserts)
+			}
+			// Only whole string values match, so substrings and list items are kept
+			if got := inserts[0].Args[:3]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bio, name, nickname = %#v, want %#v", got, tt.want)
+			}
+			if got := arrayLiteral(t, inserts[0].Args[3]); got != `{"<null>"}` {
+				t.Errorf("tags = %v, want the list item kept", got)
+			}
+		})
+	}
+}
+
+func TestNullSentinelTypedAndCSV(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.NullSentinel = `\N`
+	config.TypeAwareBinding = true
+	config.FileExtensions = append(config.FileExtensions, ".csv")
+	fm, fake := newFakeManager(t, config)
+	fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+		if isColumnQuery(query) {
+			return columnRows(map[string]string{"id": "integer", "code": "text", "name": "text", "population": "bigint"}), nil
+		}
+		return &fakeRows{columns: []string{"id"}, rows: [][]any{{int64(1)}}}, nil
+	}
+
+	dir := t.TempDir()
+	writeFixtureIn(t, dir, "countries.csv", "code,name,population:int\nde,\\N,\\N\n")
+	if err := fm.LoadFixturesFromDir(dir); err != nil {
+		t.Fatalf("LoadFixturesFromDir() error = %v", err)
+	}
+
+	inserts := fake.queries("INSERT")
+	if len(inserts) != 1 || len(inserts[0].Args) != 3 {
+		t.Fatalf("inserts = %v, want one row of 3 columns", inserts)
+	}
+	if code := inserts[0].Args[0]; code != "de" {
+		t.Errorf("code = %#v, want de", code)
+	}
+	if name := inserts[0].Args[1]; name != (sql.NullString{}) {
+		t.Errorf("name = %#v, want the sentinel of a string cell bound as a typed NULL", name)
+	}
+	if population := inserts[0].Args[2]; population != (sql.NullInt64{}) {
+		t.Errorf("population = %#v, want the sentinel of an int cell bound as a typed NULL", population)
+	}
+}
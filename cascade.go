@@ -0,0 +1,86 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// foreignKey is a foreign key between two tables and its ON DELETE rule
+type foreignKey struct {
+	Child      string
+	Parent     string
+	DeleteRule string
+}
+
+// cascadeDependencies adds the foreign keys between the tables to the cleanup dependencies in before,
+// which lists for each table the tables deleted before it
+// Children of a cascading key are deleted after their parent, the cascade has already removed the rows
+// referencing fixture parents and the child delete only removes the remaining ones
+// Other rules (RESTRICT, NO ACTION, ...) require the children to be deleted first
+func (fm *FixtureManager) cascadeDependencies(
+	ctx context.Context, q querier, tableNames []string, before map[string][]string,
+) error {
+	if fm.foreignKeys == nil {
+		foreignKeys, err := queryForeignKeys(ctx, q)
+		if err != nil {
+			return err
+		}
+		fm.foreignKeys = foreignKeys
+	}
+
+	for _, fk := range fm.foreignKeys {
+		if fk.Child == fk.Parent || !slices.Contains(tableNames, fk.Child) || !slices.Contains(tableNames, fk.Parent) {
+			continue
+		}
+
+		if fk.DeleteRule == "CASCADE" {
+			// Replace a declared child-first order, the cascade takes care of it
+			before[fk.Parent] = slices.DeleteFunc(before[fk.Parent], func(t string) bool { return t == fk.Child })
+			if !slices.Contains(before[fk.Child], fk.Parent) {
+				before[fk.Child] = append(before[fk.Child], fk.Parent)
+			}
+			continue
+		}
+		if !slices.Contains(before[fk.Parent], fk.Child) {
+			before[fk.Parent] = append(before[fk.Parent], fk.Child)
+		}
+	}
+	return nil
+}
+
+// queryForeignKeys lists the foreign keys of the database
+// Tables of the current schema are named without their schema, like unqualified fixture table names
+func queryForeignKeys(ctx context.Context, q querier) ([]foreignKey, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT DISTINCT
+			CASE WHEN tc.table_schema = current_schema() THEN tc.table_name
+				ELSE tc.table_schema || '.' || tc.table_name END,
+			CASE WHEN ctu.table_schema = current_schema() THEN ctu.table_name
+				ELSE ctu.table_schema || '.' || ctu.table_name END,
+			rc.delete_rule
+		FROM information_schema.referential_constraints rc
+		JOIN information_schema.table_constraints tc
+			ON tc.constraint_schema = rc.constraint_schema AND tc.constraint_name = rc.constraint_name
+		JOIN information_schema.constraint_table_usage ctu
+			ON ctu.constraint_schema = rc.constraint_schema AND ctu.constraint_name = rc.constraint_name`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	// Non-nil even without foreign keys, so the result is cached
+	foreignKeys := []foreignKey{}
+	for rows.Next() {
+		var fk foreignKey
+		if err := rows.Scan(&fk.Child, &fk.Parent, &fk.DeleteRule); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		foreignKeys = append(foreignKeys, fk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read foreign keys: %w", err)
+	}
+	return foreignKeys, nil
+}
@@ -0,0 +1,152 @@
+package testkit
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// cascadeFixture loads a chain of tables whose foreign keys are answered by cascadeManager
+const cascadeFixture = `
+users:
+  - name: alice
+orders:
+  - total: 10
+order_items:
+  - sku: book
+`
+
+// cascadeManager returns a manager on a fake database where orders cascade from users and order items restrict
+// deleting their orders
+func cascadeManager(t *testing.T, cascadeAware bool) (*FixtureManager, *fakeDB) {
+	t.Helper()
+
+	config := DefaultFixtureConfig()
+	config.CascadeAwareCleanup = cascadeAware
+	fm, fake := newFakeManager(t, config)
+	fm.ConfigureTableDependencies("orders", []string{"users"})
+	fm.ConfigureTableDependencies("order_items", []string{"orders"})
+	fake.returnIDs()
+	returnIDs := fake.onQuery
+	fake.onQuery = func(query string, args []any) (*fakeRows, error) {
+		if isForeignKeyQuery(query) {
+			return &fakeRows{
+				columns: []string{"child", "parent", "delete_rule"},
+				rows: [][]any{
+					{"orders", "users", "CASCADE"},
+					{"order_items", "orders", "RESTRICT"},
+					{"audit_log", "users", "NO ACTION"},
+				},
+			}, nil
+		}
+		return returnIDs(query, args)
+	}
+	return fm, fake
+}
+
+// isForeignKeyQuery reports whether a query reads the foreign keys of the database
+func isForeignKeyQuery(query string) bool {
+	return strings.Contains(query, "information_schema.referential_constraints")
+}
+
+// countForeignKeyQueries returns how many times the foreign keys were queried
+func countForeignKeyQueries(fake *fakeDB) int {
+	n := 0
+	for _, query := range fake.queryTexts("") {
+		if isForeignKeyQuery(query) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestCascadeAwareCleanupOrder(t *testing.T) {
+	fm, fake := cascadeManager(t, true)
+	fixture := writeFixture(t, "orders.yml", cascadeFixture)
+
+	for run := 0; run < 2; run++ {
+		if err := fm.LoadYAMLFixtures(fixture); err != nil {
+			t.Fatalf("LoadYAMLFixtures() error = %v", err)
+		}
+		fake.reset()
+		if err := fm.CleanupFixtures(); err != nil {
+			t.Fatalf("CleanupFixtures() error = %v", err)
+		}
+
+		deleted := deletedTables(fake)
+		position := func(table string) int { return slices.Index(deleted, table) }
+		if len(deleted) != 3 {
+			t.Fatalf("run %d: deleted tables = %v, want users, orders and order_items", run, deleted)
+		}
+		// The cascading key lets users go first, the restricting one keeps order items before their orders
+		if position("users") > position("orders") {
+			t.Errorf("run %d: deleted tables = %v, want users before the orders that cascade from it", run, deleted)
+		}
+		if position("order_items") > position("orders") {
+			t.Errorf("run %d: deleted tables = %v, want order_items before the orders they restrict", run, deleted)
+		}
+	}
+
+	// The foreign keys were queried by the first cleanup only
+	fake.reset()
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+	if n := countForeignKeyQueries(fake); n != 0 {
+		t.Errorf("foreign key queries after the first cleanup = %d, want the cached keys used", n)
+	}
+}
+
+func TestCleanupWithoutCascadeAwareness(t *testing.T) {
+	fm, fake := cascadeManager(t, false)
+	if err := fm.LoadYAMLFixtures(writeFixture(t, "orders.yml", cascadeFixture)); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+
+	if n := countForeignKeyQueries(fake); n != 0 {
+		t.Errorf("foreign key queries = %d, want none without CascadeAwareCleanup", n)
+	}
+	want := []string{"order_items", "orders", "users"}
+	if deleted := deletedTables(fake); !slices.Equal(deleted, want) {
+		t.Errorf("deleted tables = %v, want the reverse load order %v", deleted, want)
+	}
+}
+
+func TestCascadeAwareCleanupPostgres(t *testing.T) {
+	db := newPostgresDB(t,
+		"CREATE TABLE users (id serial PRIMARY KEY, name text NOT NULL)",
+		"CREATE TABLE orders (id serial PRIMARY KEY, user_id int NOT NULL REFERENCES users ON DELETE CASCADE)",
+		"CREATE TABLE order_items (id serial PRIMARY KEY, order_id int NOT NULL REFERENCES orders ON DELETE RESTRICT)",
+	)
+	config := DefaultFixtureConfig()
+	config.CascadeAwareCleanup = true
+	fm := NewFixtureManagerWithConfig(db, config)
+
+	fixture := writeFixture(t, "orders.yml", `
+users:
+  - _alias: alice
+    name: alice
+orders:
+  - _alias: first
+    user_id: $users.alice.id
+order_items:
+  - order_id: $orders.first.id
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+	for _, table := range []string{"users", "orders", "order_items"} {
+		if count := countTableRows(t, db, table); count != 0 {
+			t.Errorf("%s has %d rows after cleanup, want 0", table, count)
+		}
+	}
+}
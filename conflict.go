@@ -0,0 +1,66 @@
+package testkit
+
+import (
+	"slices"
+	"strings"
+)
+
+// ConflictMode controls what happens when a fixture row collides with an existing row on its primary key
+type ConflictMode int
+
+const (
+	// ConflictFail fails the load on duplicate keys (default)
+	ConflictFail ConflictMode = iota
+	// ConflictDoNothing keeps the existing row, the fixture row is skipped and not tracked for cleanup
+	// Without RETURNING (MySQL, SQLite, tables without an id) none of the rows of a BatchSize batch with a skipped
+	// row are tracked, since the skipped ones are unknown
+	ConflictDoNothing
+	// ConflictUpdate overwrites the non-key columns given by the fixture row, the row is tracked for cleanup
+	ConflictUpdate
+)
+
+// SetConflictMode selects how fixture loading handles rows whose primary key already exists
+func (fm *FixtureManager) SetConflictMode(mode ConflictMode) {
+	fm.config.ConflictMode = mode
+}
+
+// conflictClause returns the clause appended to an INSERT of the columns, empty for ConflictFail
+// The conflict target is the table's primary key, updated columns are the inserted non-key columns
+func (fm *FixtureManager) conflictClause(tableName string, columns []string) string {
+	if fm.config.ConflictMode == ConflictFail {
+		return ""
+	}
+
+	dialect := fm.config.Dialect
+	primaryKeys := fm.getPrimaryKeys(tableName)
+	var updates []string
+	if fm.config.ConflictMode == ConflictUpdate {
+		for _, column := range columns {
+			if slices.Contains(primaryKeys, column) {
+				continue
+			}
+			quoted := dialect.QuoteIdentifier(column)
+			if dialect == DialectMySQL {
+				updates = append(updates, quoted+" = VALUES("+quoted+")")
+			} else {
+				updates = append(updates, quoted+" = EXCLUDED."+quoted)
+			}
+		}
+	}
+	primaryKeys = dialect.quoteIdentifiers(primaryKeys)
+
+	if dialect == DialectMySQL {
+		// MySQL has no conflict target, any unique key matches
+		if len(updates) == 0 {
+			// Assigning a key column to itself is a no-op that keeps the existing row
+			return " ON DUPLICATE KEY UPDATE " + primaryKeys[0] + " = " + primaryKeys[0]
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	}
+
+	target := " ON CONFLICT (" + strings.Join(primaryKeys, ", ") + ")"
+	if len(updates) == 0 {
+		return target + " DO NOTHING"
+	}
+	return target + " DO UPDATE SET " + strings.Join(updates, ", ")
+}
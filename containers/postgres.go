@@ -0,0 +1,50 @@
+// Package containers starts throwaway databases for testkit suites with testcontainers-go
+// It is a module of its own, so only suites importing it depend on Docker and testcontainers
+package containers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+// DefaultPostgresImage is the image started by StartPostgresContainer, override it with testcontainers.WithImage
+const DefaultPostgresImage = "postgres:16-alpine"
+
+// StartPostgresContainer starts a Postgres container and waits until it accepts connections
+// It returns a connection string for RunnerConfig.DBConnectionString and a function removing the container,
+// meant for RunnerConfig.AfterCleanup; opts customize the container, e.g. postgres.WithInitScripts
+func StartPostgresContainer(
+	ctx context.Context, opts ...testcontainers.ContainerCustomizer,
+) (connString string, terminate func(), err error) {
+	opts = append([]testcontainers.ContainerCustomizer{
+		postgres.WithDatabase("testkit"),
+		postgres.WithUsername("testkit"),
+		postgres.WithPassword("testkit"),
+		postgres.BasicWaitStrategies(),
+	}, opts...)
+
+	container, err := postgres.Run(ctx, DefaultPostgresImage, opts...)
+	if err != nil {
+		// A container that started but never became ready is returned too and must be removed
+		terminateContainer(container)
+		return "", nil, fmt.Errorf("failed to start postgres container: %w", err)
+	}
+
+	connString, err = container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		terminateContainer(container)
+		return "", nil, fmt.Errorf("failed to get postgres connection string: %w", err)
+	}
+	return connString, func() { terminateContainer(container) }, nil
+}
+
+// terminateContainer removes a container, logging failures as cleanup must go on
+func terminateContainer(container testcontainers.Container) {
+	if err := testcontainers.TerminateContainer(container); err != nil {
+		log.Printf("Warning: failed to terminate postgres container: %v", err)
+	}
+}
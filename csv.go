@@ -0,0 +1,154 @@
+package testkit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// fileParser is implemented by parsers that need the name of the fixture file, e.g. to name its table
+type fileParser interface {
+	parseFile(name string, content []byte) (TableFixtures, error)
+}
+
+// CSVParser parses CSV fixtures holding the rows of a single table, named after the file without its extension
+// The header row lists the columns, optionally with a type hint such as "age:int"; cells are strings by default,
+// int, float, bool, json and timestamp cells are converted and an empty typed cell is NULL
+// A cell starting with a value directive such as "!default" or "!nextval users_id_seq" is resolved like in YAML
+type CSVParser struct {
+	// Table receiving the rows, defaults to the file name without its extension
+	Table string
+	// Cells equal to it are NULL whatever their type hint, the default parser of .csv files uses FixtureConfig's
+	NullSentinel string
+}
+
+// Parse implements FixtureParser, it requires Table as the content alone does not name the table
+func (p CSVParser) Parse(content []byte) (TableFixtures, error) {
+	if p.Table == "" {
+		return nil, errors.New("CSVParser needs a Table when it is not given the file name")
+	}
+	return p.parseCSV(p.Table, content)
+}
+
+// parseFile implements fileParser
+func (p CSVParser) parseFile(name string, content []byte) (TableFixtures, error) {
+	table := p.Table
+	if table == "" {
+		base := path.Base(name)
+		table = strings.TrimSuffix(base, path.Ext(base))
+	}
+	return p.parseCSV(table, content)
+}
+
+// csvColumn is a column of the CSV header and the type hint its cells are converted with
+type csvColumn struct {
+	name string
+	hint string
+}
+
+// parseCSV parses the CSV rows of a table
+func (p CSVParser) parseCSV(table string, content []byte) (TableFixtures, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return TableFixtures{table: nil}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make([]csvColumn, len(header))
+	for i, field := range header {
+		name, hint, _ := strings.Cut(strings.TrimSpace(field), ":")
+		column := csvColumn{name: strings.TrimSpace(name), hint: strings.TrimSpace(hint)}
+		if column.name == "" {
+			return nil, fmt.Errorf("column %d of the CSV header has no name", i+1)
+		}
+		if _, ok := csvConverters[column.hint]; !ok {
+			return nil, fmt.Errorf("column %s has unknown type hint %q", column.name, column.hint)
+		}
+		columns[i] = column
+	}
+
+	var records []map[string]any
+	for {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV rows: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		record := make(map[string]any, len(columns))
+		for i, column := range columns {
+			value, err := p.csvValue(column.hint, fields[i])
+			if err != nil {
+				return nil, fmt.Errorf("line %d column %s: %w", line, column.name, err)
+			}
+			record[column.name] = value
+		}
+		records = append(records, record)
+	}
+	return TableFixtures{table: records}, nil
+}
+
+// csvConverters convert CSV cells by type hint, no hint keeps the cell as a string
+var csvConverters = map[string]func(cell string) (any, error){
+	"": func(cell string) (any, error) {
+		return cell, nil
+	},
+	"string": func(cell string) (any, error) {
+		return cell, nil
+	},
+	"int": func(cell string) (any, error) {
+		return strconv.ParseInt(cell, 10, 64)
+	},
+	"float": func(cell string) (any, error) {
+		return strconv.ParseFloat(cell, 64)
+	},
+	"bool": func(cell string) (any, error) {
+		return strconv.ParseBool(cell)
+	},
+	// Decoded like JSON fixtures, so objects and arrays are bound like YAML maps and lists
+	"json": func(cell string) (any, error) {
+		decoder := json.NewDecoder(strings.NewReader(cell))
+		decoder.UseNumber()
+		var value any
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return convertJSONNumbers(value), nil
+	},
+	// Other cells such as NOW()+1h stay strings, so value tokens resolve at load time
+	"timestamp": func(cell string) (any, error) {
+		if parsed, err := parseTimestamp(cell); err == nil {
+			return parsed, nil
+		}
+		return cell, nil
+	},
+}
+
+// csvValue converts a CSV cell, resolving value directives first
+func (p CSVParser) csvValue(hint, cell string) (any, error) {
+	if p.NullSentinel != "" && cell == p.NullSentinel {
+		return nil, nil
+	}
+	if strings.HasPrefix(cell, "!") {
+		tag, arg, _ := strings.Cut(cell, " ")
+		if directive, ok := valueDirectives[tag]; ok {
+			return directive(arg)
+		}
+	}
+	if cell == "" && hint != "" && hint != "string" {
+		return nil, nil
+	}
+	return csvConverters[hint](cell)
+}
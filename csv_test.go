@@ -0,0 +1,113 @@
+package testkit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSVParser(t *testing.T) {
+	content := `code,name , population:int,area:float,eu:bool,metadata:json,founded:timestamp,id
+de,Germany,83200000,357588.5,true,"{""capital"": ""Berlin"", ""languages"": [""de""]}",1949-05-23,!default
+ch,"Zürich, ""CH""",,,,,NOW(),!nextval countries_id_seq
+`
+	fixtures, err := CSVParser{}.parseFile("fixtures/countries.csv", []byte(content))
+	if err != nil {
+		t.Fatalf("parseFile() error = %v", err)
+	}
+
+	want := TableFixtures{"countries": {
+		{
+			"code":       "de",
+			"name":       "Germany",
+			"population": int64(83200000),
+			"area":       357588.5,
+			"eu":         true,
+			"metadata":   map[string]any{"capital": "Berlin", "languages": []any{"de"}},
+			"founded":    time.Date(1949, 5, 23, 0, 0, 0, 0, time.UTC),
+			"id":         sqlExpression("DEFAULT"),
+		},
+		{
+			// Empty typed cells are NULL, timestamps that do not parse are kept for the value tokens
+			"code":       "ch",
+			"name":       `Zürich, "CH"`,
+			"population": nil,
+			"area":       nil,
+			"eu":         nil,
+			"metadata":   nil,
+			"founded":    "NOW()",
+			"id":         sqlExpression("nextval('countries_id_seq')"),
+		},
+	}}
+	if !reflect.DeepEqual(fixtures, want) {
+		t.Errorf("parseFile() = %#v, want %#v", fixtures, want)
+	}
+}
+
+func TestCSVParserTable(t *testing.T) {
+	parser := CSVParser{Table: "reference.countries"}
+	for name, parse := range map[string]func() (TableFixtures, error){
+		"Parse":     func() (TableFixtures, error) { return parser.Parse([]byte("code\nde\n")) },
+		"parseFile": func() (TableFixtures, error) { return parser.parseFile("countries.csv", []byte("code\nde\n")) },
+	} {
+		fixtures, err := parse()
+		if err != nil {
+			t.Fatalf("%s() error = %v", name, err)
+		}
+		if rows := fixtures["reference.countries"]; len(rows) != 1 || rows[0]["code"] != "de" {
+			t.Errorf("%s() = %v, want the row in the configured table", name, fixtures)
+		}
+	}
+
+	if _, err := (CSVParser{}).Parse([]byte("code\nde\n")); err == nil {
+		t.Error("Parse() without Table error = nil, want the missing table reported")
+	}
+	fixtures, err := CSVParser{}.parseFile("countries.csv", nil)
+	if err != nil || len(fixtures) != 1 || fixtures["countries"] != nil {
+		t.Errorf("parseFile() of an empty file = %v, %v, want the table without rows", fixtures, err)
+	}
+}
+
+func TestCSVParserErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "unknown hint", content: "code:char\nde\n", want: `column code has unknown type hint "char"`},
+		{name: "unnamed column", content: "code,:int\nde,1\n", want: "column 2 of the CSV header has no name"},
+		{name: "invalid int", content: "code,population:int\nde,1\nch,many\n", want: "line 3 column population"},
+		{name: "invalid json", content: "metadata:json\n{\n", want: "line 2 column metadata: invalid JSON"},
+		{name: "missing cells", content: "code,name\nde\n", want: "failed to read CSV rows"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CSVParser{}.parseFile("countries.csv", []byte(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseFile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMixedYAMLAndCSVDirectory(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.FileExtensions = append(config.FileExtensions, ".csv")
+	fm, fake := newFakeManager(t, config)
+	fake.returnIDs()
+
+	dir := t.TempDir()
+	writeFixtureIn(t, dir, "countries.csv", "code,name\nde,Germany\nch,Switzerland\n")
+	writeFixtureIn(t, dir, "users.yml", "users:\n  - name: alice\n    country: de\n")
+	if err := fm.LoadFixturesFromDir(dir); err != nil {
+		t.Fatalf("LoadFixturesFromDir() error = %v", err)
+	}
+
+	if inserts := fake.queries(`INSERT INTO "countries"`); len(inserts) != 2 {
+		t.Errorf("country inserts = %v, want the 2 CSV rows in the table named after the file", inserts)
+	}
+	if inserts := fake.queries(`INSERT INTO "users"`); len(inserts) != 1 {
+		t.Errorf("user inserts = %v, want the YAML file loaded next to the CSV file", inserts)
+	}
+}
@@ -0,0 +1,159 @@
+package testkit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/lib/pq"
+)
+
+// maxDatabaseNameLength is the PostgreSQL identifier length limit
+const maxDatabaseNameLength = 63
+
+// createDatabaseAttempts bounds retries when a generated database name is already taken
+const createDatabaseAttempts = 3
+
+// DatabaseNameData is the data available to RunnerConfig.DatabaseNameTemplate
+type DatabaseNameData struct {
+	// Pkg is the sanitized name of the test binary, e.g. "users" for users.test
+	Pkg string
+	// Rand is a random hex suffix unique to this run
+	Rand string
+}
+
+var (
+	invalidNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+	dbnameParam      = regexp.MustCompile(`(^|\s)dbname=('(?:[^'\\]|\\.)*'|\S*)`)
+)
+
+// ephemeralDatabase is a database created for a single test run and dropped at cleanup
+type ephemeralDatabase struct {
+	name     string
+	adminDSN string
+}
+
+// createEphemeralDatabase creates a database named from the configured template
+// Name collisions from concurrent runs are retried with a fresh random suffix
+func createEphemeralDatabase(ctx context.Context, config *RunnerConfig) (*ephemeralDatabase, error) {
+	tmpl, err := template.New("database").Parse(config.DatabaseNameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database name template: %w", err)
+	}
+
+	adminDSN := config.AdminDSN
+	if adminDSN == "" {
+		adminDSN = config.DBConnectionString
+	}
+	admin, err := sql.Open("postgres", adminDSN)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to admin database: %w", err)
+	}
+	defer admin.Close()
+
+	for attempt := 1; ; attempt++ {
+		name, err := renderDatabaseName(tmpl)
+		if err != nil {
+			return nil, err
+		}
+
+		//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+		_, err = admin.ExecContext(ctx, fmt.Sprintf("CREATE DATABASE %s", pq.QuoteIdentifier(name)))
+		if err == nil {
+			return &ephemeralDatabase{name: name, adminDSN: adminDSN}, nil
+		}
+
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "42P04" && attempt < createDatabaseAttempts {
+			continue // duplicate_database, another run picked the same name
+		}
+		return nil, fmt.Errorf("failed to create database %s: %w", name, err)
+	}
+}
+
+// dsn returns the connection string for the ephemeral database based on the given one
+func (d *ephemeralDatabase) dsn(base string) string {
+	if base == "" {
+		base = d.adminDSN
+	}
+	return withDatabaseName(base, d.name)
+}
+
+// drop removes the ephemeral database
+func (d *ephemeralDatabase) drop(ctx context.Context) error {
+	admin, err := sql.Open("postgres", d.adminDSN)
+	if err != nil {
+		return fmt.Errorf("failed to connect to admin database: %w", err)
+	}
+	defer admin.Close()
+
+	//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+	if _, err := admin.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s", pq.QuoteIdentifier(d.name))); err != nil {
+		return fmt.Errorf("failed to drop database %s: %w", d.name, err)
+	}
+	return nil
+}
+
+// renderDatabaseName executes the name template with a fresh random suffix
+func renderDatabaseName(tmpl *template.Template) (string, error) {
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate database name suffix: %w", err)
+	}
+
+	pkg := strings.TrimSuffix(filepath.Base(os.Args[0]), ".test")
+	data := DatabaseNameData{
+		Pkg:  strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(pkg), "_"), "_"),
+		Rand: hex.EncodeToString(suffix),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render database name: %w", err)
+	}
+
+	name := buf.String()
+	if name == "" {
+		return "", fmt.Errorf("database name template rendered an empty name")
+	}
+	if len(name) > maxDatabaseNameLength {
+		// Keep the end of the name, which holds the random suffix in typical templates
+		name = name[len(name)-maxDatabaseNameLength:]
+	}
+	return name, nil
+}
+
+// withDatabaseName returns the connection string with its database replaced
+// Both URL (postgres://...) and key/value (host=... dbname=...) forms are supported
+func withDatabaseName(dsn, name string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if u, err := url.Parse(dsn); err == nil {
+			u.Path = "/" + name
+			return u.String()
+		}
+	}
+
+	param := "dbname=" + quoteDSNValue(name)
+	if dbnameParam.MatchString(dsn) {
+		return dbnameParam.ReplaceAllString(dsn, "${1}"+strings.ReplaceAll(param, "$", "$$"))
+	}
+	return strings.TrimSpace(dsn + " " + param)
+}
+
+// quoteDSNValue quotes a key/value connection string value when needed
+func quoteDSNValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
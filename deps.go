@@ -0,0 +1,168 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// tableDependencies returns the declared and inferred dependencies of each table, limited to the given tables
+// referenced holds the inferred dependencies, fm.referencedTables or a copy of it, see referencedSnapshot
+// extra adds dependencies that only hold for one load, such as the order of an ordered fixture file, and may be nil
+// Dependencies outside the set are expected to be loaded already
+func (fm *FixtureManager) tableDependencies(
+	tableNames []string, referenced, extra map[string][]string,
+) map[string][]string {
+	present := make(map[string]bool, len(tableNames))
+	for _, tableName := range tableNames {
+		present[tableName] = true
+	}
+
+	dependencies := make(map[string][]string, len(tableNames))
+	for _, tableName := range tableNames {
+		declared := fm.tableConfigs[tableName].DependsOn
+		candidates := slices.Concat(declared, referenced[tableName], extra[tableName])
+		for _, dependency := range candidates {
+			if present[dependency] && dependency != tableName && !slices.Contains(dependencies[tableName], dependency) {
+				dependencies[tableName] = append(dependencies[tableName], dependency)
+			}
+		}
+	}
+	return dependencies
+}
+
+// inferDependencies records the tables referenced by alias references as dependencies of the referencing tables
+// They are kept for later loads and for cleanup, which deletes referencing rows first
+func (fm *FixtureManager) inferDependencies(fixtures TableFixtures) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	for tableName, records := range fixtures {
+		for _, record := range records {
+			for _, value := range record {
+				s, ok := value.(string)
+				if !ok {
+					continue
+				}
+				match := aliasReference.FindStringSubmatch(s)
+				if match == nil || match[1] == tableName || slices.Contains(fm.referencedTables[tableName], match[1]) {
+					continue
+				}
+				fm.referencedTables[tableName] = append(fm.referencedTables[tableName], match[1])
+			}
+		}
+	}
+}
+
+// referencedSnapshot returns a copy of the inferred dependencies, for loads that do not hold fm.mu
+func (fm *FixtureManager) referencedSnapshot() map[string][]string {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	snapshot := make(map[string][]string, len(fm.referencedTables))
+	for tableName, referenced := range fm.referencedTables {
+		snapshot[tableName] = slices.Clone(referenced)
+	}
+	return snapshot
+}
+
+// orderDependencies makes each table of an ordered fixture file depend on the table listed before it
+// Unlike inferred dependencies they only hold for the load of that file: another file may list the same tables in
+// another order, so keeping them would report cycles that do not exist
+func orderDependencies(order []string) map[string][]string {
+	dependencies := make(map[string][]string, len(order))
+	for i := 1; i < len(order); i++ {
+		tableName, previous := order[i], order[i-1]
+		if tableName != previous && !slices.Contains(dependencies[tableName], previous) {
+			dependencies[tableName] = append(dependencies[tableName], previous)
+		}
+	}
+	return dependencies
+}
+
+// dependencyOrder sorts the tables so that every table comes after the tables it depends on
+// Tables without a dependency between them keep their relative order, it returns an error on a cycle
+func (fm *FixtureManager) dependencyOrder(tableNames []string, extra map[string][]string) ([]string, error) {
+	return topologicalSort(tableNames, fm.tableDependencies(tableNames, fm.referencedSnapshot(), extra))
+}
+
+// recordLoadOrder remembers the position of a table the first time rows are inserted into it, see cleanupOrder
+func (fm *FixtureManager) recordLoadOrder(tableName string) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	if !slices.Contains(fm.loadOrder, tableName) {
+		fm.loadOrder = append(fm.loadOrder, tableName)
+	}
+}
+
+// cleanupOrder sorts the tables so that every table comes before the tables it depends on
+// Tables without a dependency between them are deleted in reverse load order, which also holds for foreign keys
+// only expressed by the order of an ordered fixture file. With CascadeAwareCleanup foreign keys of the database
+// adjust the order, see cascadeDependencies. The caller must hold fm.mu
+func (fm *FixtureManager) cleanupOrder(ctx context.Context, q querier, tableNames []string) ([]string, error) {
+	tableNames = slices.Clone(tableNames)
+	slices.SortStableFunc(tableNames, func(a, b string) int {
+		// Tables never loaded have index -1 and come last
+		return slices.Index(fm.loadOrder, b) - slices.Index(fm.loadOrder, a)
+	})
+
+	// Invert the load dependencies: a table is deleted after the tables depending on it
+	before := make(map[string][]string, len(tableNames))
+	for tableName, parents := range fm.tableDependencies(tableNames, fm.referencedTables, nil) {
+		for _, parent := range parents {
+			before[parent] = append(before[parent], tableName)
+		}
+	}
+
+	if fm.config.CascadeAwareCleanup {
+		if err := fm.cascadeDependencies(ctx, q, tableNames, before); err != nil {
+			return nil, err
+		}
+	}
+
+	return topologicalSort(tableNames, before)
+}
+
+// topologicalSort orders the tables so that every table comes after the tables listed for it in dependencies
+// Tables without a dependency between them keep their relative order, it returns an error on a cycle
+func topologicalSort(tableNames []string, dependencies map[string][]string) ([]string, error) {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(tableNames))
+	ordered := make([]string, 0, len(tableNames))
+	var path []string
+
+	var visit func(tableName string) error
+	visit = func(tableName string) error {
+		switch state[tableName] {
+		case visited:
+			return nil
+		case visiting:
+			// Report the cycle starting from the first occurrence of the table on the path
+			cycle := append(path[slices.Index(path, tableName):], tableName)
+			return fmt.Errorf("dependency cycle between tables: %s", strings.Join(cycle, " -> "))
+		}
+
+		state[tableName] = visiting
+		path = append(path, tableName)
+		for _, dependency := range dependencies[tableName] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[tableName] = visited
+		ordered = append(ordered, tableName)
+		return nil
+	}
+
+	for _, tableName := range tableNames {
+		if err := visit(tableName); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,162 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// deletedTables returns the tables of the logged DELETE statements in execution order
+func deletedTables(fake *fakeDB) []string {
+	var tables []string
+	for _, query := range fake.queryTexts("DELETE FROM") {
+		tables = append(tables, strings.Trim(strings.Fields(query)[2], `"`))
+	}
+	return tables
+}
+
+func TestOrderedFilesOnlyOrderTheirOwnLoad(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+
+	dir := t.TempDir()
+	first := writeFixtureIn(t, dir, "first.yml", `
+- table: users
+  rows:
+    - name: alice
+- table: accounts
+  rows:
+    - name: main
+`)
+	second := writeFixtureIn(t, dir, "second.yml", `
+- table: accounts
+  rows:
+    - name: spare
+- table: users
+  rows:
+    - name: bob
+`)
+	if err := fm.LoadYAMLFixtures(first); err != nil {
+		t.Fatalf("LoadYAMLFixtures(first) error = %v", err)
+	}
+	// The reverse order of the second file is no cycle, the order of the first file did not outlive its load
+	if err := fm.LoadYAMLFixtures(second); err != nil {
+		t.Fatalf("LoadYAMLFixtures(second) error = %v", err)
+	}
+
+	var inserted []string
+	for _, query := range fake.queryTexts("INSERT") {
+		inserted = append(inserted, strings.Trim(strings.Fields(query)[2], `"`))
+	}
+	if want := []string{"users", "accounts", "accounts", "users"}; !slices.Equal(inserted, want) {
+		t.Errorf("inserted tables = %v, want %v", inserted, want)
+	}
+
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+	// users was loaded first, so it is deleted last
+	if got, want := deletedTables(fake), []string{"accounts", "users"}; !slices.Equal(got, want) {
+		t.Errorf("deleted tables = %v, want %v", got, want)
+	}
+}
+
+func TestCleanupOrderKeepsInferredDependencies(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+
+	fixture := writeFixture(t, "posts.yml", `
+users:
+  - _alias: alice
+    name: alice
+posts:
+  - user_id: $users.alice.id
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	// users is loaded last, yet posts references it and is deleted first
+	if err := fm.LoadYAMLFixtures(writeFixture(t, "users.yml", "users:\n  - name: bob\n")); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+	if got, want := deletedTables(fake), []string{"posts", "users"}; !slices.Equal(got, want) {
+		t.Errorf("deleted tables = %v, want %v", got, want)
+	}
+}
+
+func TestDependencyOrderReportsCycles(t *testing.T) {
+	fm, _ := newFakeManager(t, nil)
+	fm.ConfigureTableDependencies("a", []string{"b"})
+	fm.ConfigureTableDependencies("b", []string{"a"})
+
+	_, err := fm.dependencyOrder([]string{"a", "b"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("dependencyOrder() error = %v, want the cycle a -> b -> a", err)
+	}
+}
+
+func TestConcurrentLoadsOnOneManager(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+
+	fixtures := make([]string, 4)
+	for i := range fixtures {
+		fixtures[i] = writeFixture(t, fmt.Sprintf("load_%d.yml", i), fmt.Sprintf(`
+users_%[1]d:
+  - _alias: alice
+    name: alice
+posts_%[1]d:
+  - user_id: $users_%[1]d.alice.id
+    title: hello
+`, i))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(fixtures)+1)
+	for _, fixture := range fixtures {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- fm.LoadYAMLFixtures(fixture)
+		}()
+	}
+	// A session infers dependencies on the same manager
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		session, err := fm.Begin(context.Background())
+		if err != nil {
+			errs <- err
+			return
+		}
+		if err := session.LoadYAML(fixtures[0]); err != nil {
+			errs <- err
+			return
+		}
+		errs <- session.Rollback()
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("concurrent load error = %v", err)
+		}
+	}
+
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+	deleted := deletedTables(fake)
+	for i := range fixtures {
+		posts, users := slices.Index(deleted, fmt.Sprintf("posts_%d", i)), slices.Index(deleted, fmt.Sprintf("users_%d", i))
+		if posts < 0 || users < 0 || posts > users {
+			t.Errorf("deleted tables = %v, want posts_%d before users_%d", deleted, i, i)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package testkit
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// passwordParam matches the password of a key/value connection string
+var passwordParam = regexp.MustCompile(`(^|\s)password=('(?:[^'\\]|\\.)*'|\S*)`)
+
+// RedactDSN hides the password of a connection string in URL or key/value form
+func RedactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// Never risk printing credentials of a connection string that cannot be parsed
+			return "[unparseable connection string]"
+		}
+		// Passwords may also be passed as a query parameter
+		if query := u.Query(); query.Has("password") {
+			query.Set("password", "xxxxx")
+			u.RawQuery = query.Encode()
+		}
+		return u.Redacted()
+	}
+	return passwordParam.ReplaceAllString(dsn, "${1}password=xxxxx")
+}
+
+// Describe returns a human-readable summary of the environment the runner uses
+// Passwords in connection strings are redacted
+func (r *TestRunner) Describe() string {
+	var b strings.Builder
+	line := func(name string, value any) {
+		fmt.Fprintf(&b, "  %-18s %v\n", name+":", value)
+	}
+
+	b.WriteString("testkit environment\n")
+	if r.config.DriverName != "postgres" {
+		line("driver", r.config.DriverName)
+	}
+	line("database", RedactDSN(r.primaryDSN))
+	names := make([]string, 0, len(r.config.Databases))
+	for name := range r.config.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		line("database "+name, RedactDSN(r.config.Databases[name]))
+	}
+	if r.config.ReplicaDSN != "" {
+		line("replica", RedactDSN(r.config.ReplicaDSN))
+	}
+	line("connection pool", fmt.Sprintf("max open %d, max idle %d, max lifetime %s",
+		r.config.MaxOpenConns, r.config.MaxIdleConns, r.config.ConnMaxLifetime))
+
+	if r.config.dataOnly() {
+		line("mode", "data-only")
+	} else {
+		line("base URL", r.config.BaseURL)
+	}
+	if r.config.GRPCAddress != "" {
+		line("gRPC address", r.config.GRPCAddress)
+	}
+	if r.config.ReadinessFunc != nil {
+		line("readiness", "ReadinessFunc")
+	} else if paths, err := r.config.healthCheckPaths(); err == nil && r.config.App != nil {
+		for _, path := range paths {
+			line("health URL", r.URL(path))
+		}
+	}
+	line("fixtures dir", r.config.FixturesDir)
+	line("app", r.config.App != nil)
+	if r.config.ReadyTimeout > 0 {
+		line("ready timeout", r.config.ReadyTimeout)
+	} else {
+		line("max wait attempts", r.config.MaxWaitAttempts)
+	}
+	line("poll interval", r.config.PollInterval)
+	if r.config.PollMultiplier > 1 {
+		line("poll multiplier", r.config.PollMultiplier)
+		if r.config.MaxPollInterval > 0 {
+			line("max poll interval", r.config.MaxPollInterval)
+		}
+	}
+	line("request timeout", r.config.RequestTimeout)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
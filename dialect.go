@@ -0,0 +1,117 @@
+package testkit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect selects the SQL flavor of generated queries
+type Dialect int
+
+const (
+	// DialectPostgres uses $1, $2, ... placeholders (default)
+	DialectPostgres Dialect = iota
+	// DialectMySQL uses ? placeholders
+	// PostgreSQL specific features such as TypeAwareBinding, StatementTimeout and RETURNING are not available
+	DialectMySQL
+	// DialectSQLite uses ? placeholders, with the same limitations as DialectMySQL
+	// Bulk cleanup of large tables relies on TRUNCATE, which SQLite lacks
+	DialectSQLite
+)
+
+// Placeholder returns the bind placeholder of the n-th parameter, starting at 1
+func (d Dialect) Placeholder(n int) string {
+	if d == DialectMySQL || d == DialectSQLite {
+		return "?"
+	}
+	return "$" + strconv.Itoa(n)
+}
+
+// QuoteIdentifier quotes a table or column name, quoting the schema and table of "schema.table" separately
+// Names that already contain a quote character are assumed to be quoted and returned unchanged
+func (d Dialect) QuoteIdentifier(name string) string {
+	quote := `"`
+	if d == DialectMySQL {
+		quote = "`"
+	}
+	if strings.Contains(name, quote) {
+		return name
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = quote + part + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteIdentifiers quotes every name with QuoteIdentifier
+func (d Dialect) quoteIdentifiers(names []string) []string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = d.QuoteIdentifier(name)
+	}
+	return quoted
+}
+
+// nowExpression returns the SQL expression of the database's current timestamp shifted by offset
+// SQLite has no NOW() and evaluates 'now' in UTC
+func (d Dialect) nowExpression(offset time.Duration) string {
+	switch {
+	case d == DialectSQLite && offset == 0:
+		return "datetime('now')"
+	case d == DialectSQLite:
+		return fmt.Sprintf("datetime('now', '%s seconds')", signedSeconds(offset))
+	case offset == 0:
+		return "NOW()"
+	case d == DialectMySQL:
+		return fmt.Sprintf("DATE_ADD(NOW(), INTERVAL %d MICROSECOND)", offset.Microseconds())
+	default:
+		return fmt.Sprintf("NOW() + interval '%s'", intervalLiteral(offset))
+	}
+}
+
+// todayExpression returns the SQL expression of the database's current date shifted by offset
+// A shifted date is a timestamp, as the offset may be a fraction of a day
+func (d Dialect) todayExpression(offset time.Duration) string {
+	switch {
+	case d == DialectSQLite && offset == 0:
+		return "date('now')"
+	case d == DialectSQLite:
+		return fmt.Sprintf("datetime('now', 'start of day', '%s seconds')", signedSeconds(offset))
+	case offset == 0:
+		return "CURRENT_DATE"
+	case d == DialectMySQL:
+		return fmt.Sprintf("DATE_ADD(CURRENT_DATE, INTERVAL %d MICROSECOND)", offset.Microseconds())
+	default:
+		return fmt.Sprintf("CURRENT_DATE + interval '%s'", intervalLiteral(offset))
+	}
+}
+
+// signedSeconds formats a duration as a signed number of seconds, the form of SQLite date modifiers
+func signedSeconds(d time.Duration) string {
+	seconds := strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	if d >= 0 {
+		return "+" + seconds
+	}
+	return seconds
+}
+
+// dialectForDriver returns the dialect matching a database/sql driver name, DialectPostgres for unknown drivers
+func dialectForDriver(driverName string) Dialect {
+	switch driverName {
+	case "mysql":
+		return DialectMySQL
+	case "sqlite", "sqlite3":
+		return DialectSQLite
+	default:
+		return DialectPostgres
+	}
+}
+
+// SetDialect switches the SQL dialect used for fixture loading, cleanup and queries
+func (fm *FixtureManager) SetDialect(dialect Dialect) {
+	fm.config.Dialect = dialect
+}
@@ -0,0 +1,123 @@
+package testkit
+
+import (
+	"testing"
+)
+
+func TestDialectPlaceholder(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		n       int
+		want    string
+	}{
+		{name: "postgres first", dialect: DialectPostgres, n: 1, want: "$1"},
+		{name: "postgres tenth", dialect: DialectPostgres, n: 10, want: "$10"},
+		{name: "mysql", dialect: DialectMySQL, n: 3, want: "?"},
+		{name: "sqlite", dialect: DialectSQLite, n: 3, want: "?"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.Placeholder(tt.n); got != tt.want {
+				t.Errorf("Placeholder(%d) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialectQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		in      string
+		want    string
+	}{
+		{name: "postgres table", dialect: DialectPostgres, in: "users", want: `"users"`},
+		{name: "postgres schema table", dialect: DialectPostgres, in: "billing.invoices", want: `"billing"."invoices"`},
+		{name: "postgres already quoted", dialect: DialectPostgres, in: `"Users"`, want: `"Users"`},
+		{name: "mysql table", dialect: DialectMySQL, in: "users", want: "`users`"},
+		{name: "mysql schema table", dialect: DialectMySQL, in: "billing.invoices", want: "`billing`.`invoices`"},
+		{name: "mysql already quoted", dialect: DialectMySQL, in: "`order`", want: "`order`"},
+		{name: "sqlite table", dialect: DialectSQLite, in: "users", want: `"users"`},
+		{name: "sqlite schema table", dialect: DialectSQLite, in: "main.users", want: `"main"."users"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dialect.QuoteIdentifier(tt.in); got != tt.want {
+				t.Errorf("QuoteIdentifier(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialectForDriver(t *testing.T) {
+	tests := map[string]Dialect{
+		"postgres": DialectPostgres,
+		"pgx":      DialectPostgres,
+		"mysql":    DialectMySQL,
+		"sqlite":   DialectSQLite,
+		"sqlite3":  DialectSQLite,
+	}
+	for driverName, want := range tests {
+		if got := dialectForDriver(driverName); got != want {
+			t.Errorf("dialectForDriver(%q) = %v, want %v", driverName, got, want)
+		}
+	}
+}
+
+func TestDialectQueries(t *testing.T) {
+	tests := []struct {
+		name       string
+		dialect    Dialect
+		wantInsert string
+		wantDelete string
+	}{
+		{
+			name:       "postgres",
+			dialect:    DialectPostgres,
+			wantInsert: `INSERT INTO "users" ("id", "name") VALUES ($1, $2), ($3, $4) RETURNING "id"`,
+			wantDelete: `DELETE FROM "users" WHERE ("id" = $1) OR ("id" = $2)`,
+		},
+		{
+			name:       "mysql",
+			dialect:    DialectMySQL,
+			wantInsert: "INSERT INTO `users` (`id`, `name`) VALUES (?, ?), (?, ?)",
+			wantDelete: "DELETE FROM `users` WHERE (`id` = ?) OR (`id` = ?)",
+		},
+		{
+			name:       "sqlite",
+			dialect:    DialectSQLite,
+			wantInsert: `INSERT INTO "users" ("id", "name") VALUES (?, ?), (?, ?)`,
+			wantDelete: `DELETE FROM "users" WHERE ("id" = ?) OR ("id" = ?)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.Dialect = tt.dialect
+			config.BatchSize = 10
+			fm, fake := newFakeManager(t, config)
+			fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+				if isColumnQuery(query) {
+					return columnRows(map[string]string{"id": "integer", "name": "text"}), nil
+				}
+				return &fakeRows{columns: []string{"id"}, rows: [][]any{{int64(1)}, {int64(2)}}}, nil
+			}
+
+			fixture := writeFixture(t, "users.yml", "users:\n  - id: 1\n    name: alice\n  - id: 2\n    name: bob\n")
+			if err := fm.LoadYAMLFixtures(fixture); err != nil {
+				t.Fatalf("LoadYAMLFixtures() error = %v", err)
+			}
+			if err := fm.CleanupFixtures(); err != nil {
+				t.Fatalf("CleanupFixtures() error = %v", err)
+			}
+
+			if inserts := fake.queryTexts("INSERT"); len(inserts) != 1 || inserts[0] != tt.wantInsert {
+				t.Errorf("inserts = %q, want %q", inserts, tt.wantInsert)
+			}
+			if deletes := fake.queryTexts("DELETE"); len(deletes) != 1 || deletes[0] != tt.wantDelete {
+				t.Errorf("deletes = %q, want %q", deletes, tt.wantDelete)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"log"
+)
+
+// exec runs a statement of a fixture load or cleanup, in DryRun mode it only logs the statement and its arguments
+func (fm *FixtureManager) exec(ctx context.Context, tx *sql.Tx, query string, args ...any) error {
+	if fm.config.DryRun {
+		logDryRun(query, args)
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, query, args...)
+	return err
+}
+
+// commit commits a load or cleanup transaction, in DryRun mode it rolls back instead so nothing persists
+func (fm *FixtureManager) commit(tx *sql.Tx) error {
+	if fm.config.DryRun {
+		return tx.Rollback()
+	}
+	return tx.Commit()
+}
+
+// logDryRun logs a statement that DryRun mode did not execute
+func logDryRun(query string, args []any) {
+	if len(args) == 0 {
+		log.Printf("[dry run] %s", query)
+		return
+	}
+	log.Printf("[dry run] %s %v", query, args)
+}
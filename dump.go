@@ -0,0 +1,91 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DumpToYAML writes the rows of a table matching the given conditions as a YAML fixture
+// The output uses the TableFixtures shape so it can be loaded back with LoadYAMLFixtures
+// Rows are ordered by the configured primary keys, or by every column when none are configured,
+// and columns keep their database order
+func (fm *FixtureManager) DumpToYAML(ctx context.Context, w io.Writer, table string, where map[string]any) error {
+	//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+	query := fmt.Sprintf("SELECT * FROM %s", fm.config.Dialect.QuoteIdentifier(table))
+	clause, args := buildWhereClause(fm.config.Dialect, where, 1)
+	if clause != "" {
+		query += " WHERE " + clause
+	}
+	orderBy, err := fm.dumpOrder(ctx, table)
+	if err != nil {
+		return err
+	}
+	query += " ORDER BY " + orderBy
+
+	columns, rows, err := queryRows(ctx, fm.db, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to select rows from table %s: %w", table, err)
+	}
+
+	// Build the document by hand so columns are emitted in a deterministic order
+	records := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, row := range rows {
+		record := &yaml.Node{Kind: yaml.MappingNode}
+		for _, column := range columns {
+			value := &yaml.Node{}
+			if err := value.Encode(row[column]); err != nil {
+				return fmt.Errorf("failed to encode column %s: %w", column, err)
+			}
+			record.Content = append(record.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: column}, value)
+		}
+		records.Content = append(records.Content, record)
+	}
+	doc := &yaml.Node{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: table},
+			records,
+		},
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write YAML fixtures: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to flush YAML fixtures: %w", err)
+	}
+
+	return nil
+}
+
+// dumpOrder returns the ORDER BY list of DumpToYAML, the configured primary keys or else every column by position
+// The "id" fallback of getPrimaryKeys is not used because a table may not have such a column
+func (fm *FixtureManager) dumpOrder(ctx context.Context, table string) (string, error) {
+	if keys := fm.tableConfigs[table].PrimaryKeys; len(keys) > 0 {
+		return strings.Join(fm.config.Dialect.quoteIdentifiers(keys), ", "), nil
+	}
+
+	//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+	rows, err := fm.db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s LIMIT 0", fm.config.Dialect.QuoteIdentifier(table)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read columns of table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", fmt.Errorf("failed to read columns of table %s: %w", table, err)
+	}
+	positions := make([]string, len(columns))
+	for i := range columns {
+		positions[i] = strconv.Itoa(i + 1)
+	}
+	return strings.Join(positions, ", "), nil
+}
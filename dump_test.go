@@ -0,0 +1,56 @@
+package testkit
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDumpToYAMLOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		primaryKeys []string
+		wantOrder   string
+	}{
+		{
+			name:      "table without id orders by every column",
+			wantOrder: ` ORDER BY 1, 2`,
+		},
+		{
+			name:        "configured keys",
+			primaryKeys: []string{"key"},
+			wantOrder:   ` ORDER BY "key"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, fake := newFakeManager(t, nil)
+			if tt.primaryKeys != nil {
+				fm.ConfigureTable("settings", tt.primaryKeys)
+			}
+			fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+				rows := &fakeRows{columns: []string{"key", "value"}}
+				if !strings.HasSuffix(query, "LIMIT 0") {
+					rows.rows = [][]any{{"a", "1"}, {"b", "2"}}
+				}
+				return rows, nil
+			}
+
+			var out bytes.Buffer
+			if err := fm.DumpToYAML(context.Background(), &out, "settings", nil); err != nil {
+				t.Fatalf("DumpToYAML() error = %v", err)
+			}
+
+			queries := fake.queryTexts(`SELECT * FROM "settings" ORDER BY`)
+			if len(queries) != 1 || !strings.HasSuffix(queries[0], tt.wantOrder) {
+				t.Errorf("DumpToYAML() queries = %q, want one ending in %q", queries, tt.wantOrder)
+			}
+			want := "settings:\n  - key: a\n    value: \"1\"\n  - key: b\n    value: \"2\"\n"
+			if out.String() != want {
+				t.Errorf("DumpToYAML() = %q, want %q", out.String(), want)
+			}
+		})
+	}
+}
@@ -1,6 +1,10 @@
 package testkit
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+
 	"github.com/joho/godotenv"
 
 	"github.com/legrch/logger"
@@ -8,22 +12,47 @@ import (
 
 // LoadEnvFiles loads environment variables from the specified files
 // The files are loaded in order, with later files taking precedence over earlier ones
+// Failures are only logged, a missing file as a warning and an unparsable one as an error
 func LoadEnvFiles(envFiles ...string) {
 	for i, file := range envFiles {
 		if file == "" {
 			continue
 		}
 
-		// Use Overload for all files except the first one to ensure later files take precedence
-		var err error
-		if i == 0 {
-			err = godotenv.Load(file)
-		} else {
-			err = godotenv.Overload(file)
+		if err := loadEnvFile(file, i > 0); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				logger.Warn("Failed to load env file", "file", file, "error", err)
+			} else {
+				logger.Error("Failed to parse env file", "file", file, "error", err)
+			}
 		}
+	}
+}
 
-		if err != nil {
-			logger.Warn("Failed to load env file", "file", file, "error", err)
+// LoadEnvFilesStrict loads the files like LoadEnvFiles but stops at the first file that fails to load
+// A missing file is reported with an error matching fs.ErrNotExist, so callers can tell it from a parse error;
+// load optional override files with LoadEnvFiles instead
+func LoadEnvFilesStrict(envFiles ...string) error {
+	for i, file := range envFiles {
+		if file == "" {
+			continue
 		}
+
+		if err := loadEnvFile(file, i > 0); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("env file %s not found: %w", file, err)
+			}
+			return fmt.Errorf("failed to parse env file %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// loadEnvFile loads one env file, overriding variables that are already set when override is true
+// Every file but the first overrides, so later files take precedence
+func loadEnvFile(file string, override bool) error {
+	if override {
+		return godotenv.Overload(file)
 	}
+	return godotenv.Load(file)
 }
@@ -0,0 +1,94 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// eventualPollInterval is the delay between the queries of WaitForRow, AssertEventualRow and AssertEventuallyAbsent
+const eventualPollInterval = 100 * time.Millisecond
+
+// WaitForRow polls the table until a row matches where, returning an error with the last query once timeout has passed
+func (fm *FixtureManager) WaitForRow(
+	ctx context.Context,
+	table string,
+	where map[string]any,
+	timeout time.Duration,
+) error {
+	return fm.waitForRows(ctx, table, where, timeout, true)
+}
+
+// AssertEventualRow polls the table until a row matches where, failing the test once timeout has passed
+// Use it for rows written asynchronously, e.g. by an event consumer of the application under test
+func (fm *FixtureManager) AssertEventualRow(
+	t testing.TB,
+	ctx context.Context,
+	table string,
+	where map[string]any,
+	timeout time.Duration,
+) {
+	t.Helper()
+
+	if err := fm.waitForRows(ctx, table, where, timeout, true); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+// AssertEventuallyAbsent polls the table until no row matches where, failing the test once timeout has passed
+func (fm *FixtureManager) AssertEventuallyAbsent(
+	t testing.TB,
+	ctx context.Context,
+	table string,
+	where map[string]any,
+	timeout time.Duration,
+) {
+	t.Helper()
+
+	if err := fm.waitForRows(ctx, table, where, timeout, false); err != nil {
+		t.Fatalf("%v", err)
+	}
+}
+
+// waitForRows polls until the table has matching rows (present) or none, reporting the last query on timeout
+func (fm *FixtureManager) waitForRows(
+	ctx context.Context,
+	table string,
+	where map[string]any,
+	timeout time.Duration,
+	present bool,
+) error {
+	query, args := countQuery(fm.config.Dialect, table, where)
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	count := -1
+	var lastErr error
+	for {
+		var n int
+		if lastErr = fm.db.QueryRowContext(ctx, query, args...).Scan(&n); lastErr == nil {
+			count = n
+			if (count > 0) == present {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			expectation := "a row"
+			if !present {
+				expectation = "no row"
+			}
+			elapsed := time.Since(start).Round(time.Millisecond)
+			if count < 0 {
+				return fmt.Errorf("expected %s in table %s matching %v within %s, query %q failed after %s: %w",
+					expectation, table, where, timeout, query, elapsed, lastErr)
+			}
+			return fmt.Errorf("expected %s in table %s matching %v within %s, query %q with args %v still counted %d after %s",
+				expectation, table, where, timeout, query, args, count, elapsed)
+		case <-time.After(eventualPollInterval):
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package testkit
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countAnswers answers COUNT(*) queries with the given counts in order, repeating the last one
+func countAnswers(fake *fakeDB, counts ...int64) {
+	var calls atomic.Int64
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.onQuery = func(string, []any) (*fakeRows, error) {
+		n := min(int(calls.Add(1)), len(counts)) - 1
+		return &fakeRows{columns: []string{"count"}, rows: [][]any{{counts[n]}}}, nil
+	}
+}
+
+func TestWaitForRowUsesConfiguredDialect(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.Dialect = DialectMySQL
+	fm, fake := newFakeManager(t, config)
+	countAnswers(fake, 0, 0, 1)
+
+	if err := fm.WaitForRow(context.Background(), "orders", map[string]any{"id": 1}, time.Second); err != nil {
+		t.Fatalf("WaitForRow() error = %v", err)
+	}
+
+	queries := fake.queries("SELECT COUNT(*)")
+	if len(queries) != 3 {
+		t.Fatalf("WaitForRow() ran %d queries, want 3", len(queries))
+	}
+	want := "SELECT COUNT(*) FROM " + DialectMySQL.QuoteIdentifier("orders") + " WHERE " +
+		DialectMySQL.QuoteIdentifier("id") + " = ?"
+	if queries[0].Query != want {
+		t.Errorf("WaitForRow() query = %q, want %q", queries[0].Query, want)
+	}
+}
+
+func TestWaitForRowTimesOut(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	countAnswers(fake, 0)
+
+	err := fm.WaitForRow(context.Background(), "orders", map[string]any{"id": 1}, 250*time.Millisecond)
+	if err == nil {
+		t.Fatal("WaitForRow() error = nil, want a timeout")
+	}
+	for _, want := range []string{"expected a row in table orders", `"SELECT COUNT(*) FROM`, "still counted 0"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("WaitForRow() error = %q, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestAssertEventuallyAbsent(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	countAnswers(fake, 2, 1, 0)
+
+	fm.AssertEventuallyAbsent(t, context.Background(), "orders", map[string]any{"status": "pending"}, time.Second)
+
+	if got := len(fake.queries("SELECT COUNT(*)")); got != 3 {
+		t.Errorf("AssertEventuallyAbsent() ran %d queries, want 3", got)
+	}
+}
+
+func TestAssertEventualRow(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	countAnswers(fake, 1)
+
+	fm.AssertEventualRow(t, context.Background(), "orders", nil, time.Second)
+
+	if got := len(fake.queries("SELECT COUNT(*)")); got != 1 {
+		t.Errorf("AssertEventualRow() ran %d queries, want 1", got)
+	}
+}
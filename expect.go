@@ -0,0 +1,54 @@
+package testkit
+
+import "fmt"
+
+// expectKey is the key of the per-table row carrying expectations about the table, e.g. "_expect: {rows: 10}"
+const expectKey = "_expect"
+
+// takeExpectation returns the table's rows without its _expect entry and the expected row count, if any
+// When several entries are present, as after merging an overlay, the last one wins
+func takeExpectation(records []map[string]any) ([]map[string]any, *int64, error) {
+	var expected *int64
+	var rows []map[string]any
+	for i, record := range records {
+		spec, ok := record[expectKey]
+		if !ok {
+			rows = append(rows, record)
+			continue
+		}
+		if len(record) != 1 {
+			return nil, nil, fmt.Errorf("row %d: %s must be the only key of its entry", i, expectKey)
+		}
+
+		fields, ok := spec.(map[string]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("row %d: %s must be a map such as {rows: 10}", i, expectKey)
+		}
+		count, ok := integerValue(fields["rows"])
+		if !ok || len(fields) != 1 {
+			return nil, nil, fmt.Errorf("row %d: %s supports a single integer rows field", i, expectKey)
+		}
+		expected = &count
+	}
+
+	if expected == nil {
+		return records, nil, nil
+	}
+	return rows, expected, nil
+}
+
+// integerValue converts integral numbers decoded from YAML or JSON to int64
+func integerValue(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	case float64:
+		return int64(v), v == float64(int64(v))
+	default:
+		return 0, false
+	}
+}
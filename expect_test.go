@@ -0,0 +1,120 @@
+package testkit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpectedRowCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		wantErr string
+	}{
+		{
+			name:    "matching",
+			fixture: "users:\n  - _expect: {rows: 2}\n  - name: alice\n  - name: bob\n",
+		},
+		{
+			name:    "fewer rows",
+			fixture: "users:\n  - _expect: {rows: 3}\n  - name: alice\n  - name: bob\n",
+			wantErr: "users.yml expects 3 rows but 2 were inserted",
+		},
+		{
+			name:    "more rows",
+			fixture: "users:\n  - name: alice\n  - name: bob\n  - _expect: {rows: 1}\n",
+			wantErr: "expects 1 rows but 2 were inserted",
+		},
+		{
+			name:    "last entry wins",
+			fixture: "users:\n  - _expect: {rows: 5}\n  - name: alice\n  - _expect: {rows: 1}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, fake := newFakeManager(t, nil)
+			fake.returnIDs()
+
+			err := fm.LoadYAMLFixtures(writeFixture(t, "users.yml", tt.fixture))
+			for _, insert := range fake.queryTexts("INSERT") {
+				if strings.Contains(insert, expectKey) {
+					t.Errorf("insert %q contains the %s directive", insert, expectKey)
+				}
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadYAMLFixtures() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadYAMLFixtures() error = %v, want %q", err, tt.wantErr)
+			}
+			if commits := fake.queries("COMMIT"); len(commits) != 0 {
+				t.Errorf("commits = %d, want the mismatching load rolled back", len(commits))
+			}
+			if tables := fm.LoadedTables(); len(tables) != 0 {
+				t.Errorf("LoadedTables() = %v, want nothing tracked", tables)
+			}
+		})
+	}
+}
+
+func TestExpectedRowCountsMissConflictSkips(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.ConflictMode = ConflictDoNothing
+	fm, fake := newFakeManager(t, config)
+	fake.onQuery = func(query string, args []any) (*fakeRows, error) {
+		if isColumnQuery(query) {
+			return columnRows(map[string]string{"id": "integer"}), nil
+		}
+		// The row with id 2 already exists
+		if args[0] == 2 {
+			return &fakeRows{columns: []string{"id"}}, nil
+		}
+		return &fakeRows{columns: []string{"id"}, rows: [][]any{{args[0]}}}, nil
+	}
+
+	fixture := writeFixture(t, "users.yml", "users:\n  - _expect: {rows: 2}\n  - id: 1\n  - id: 2\n")
+	err := fm.LoadYAMLFixtures(fixture)
+	if err == nil || !strings.Contains(err.Error(), "expects 2 rows but 1 were inserted") {
+		t.Errorf("LoadYAMLFixtures() error = %v, want the skipped row counted as missing", err)
+	}
+}
+
+func TestTakeExpectationErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []map[string]any
+		wantErr string
+	}{
+		{
+			name:    "extra keys",
+			records: []map[string]any{{expectKey: map[string]any{"rows": 1}, "name": "alice"}},
+			wantErr: "row 0: _expect must be the only key of its entry",
+		},
+		{
+			name:    "not a map",
+			records: []map[string]any{{expectKey: 1}},
+			wantErr: "row 0: _expect must be a map such as {rows: 10}",
+		},
+		{
+			name:    "fractional count",
+			records: []map[string]any{{"name": "alice"}, {expectKey: map[string]any{"rows": 1.5}}},
+			wantErr: "row 1: _expect supports a single integer rows field",
+		},
+		{
+			name:    "unknown field",
+			records: []map[string]any{{expectKey: map[string]any{"rows": 1, "min": 1}}},
+			wantErr: "row 0: _expect supports a single integer rows field",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := takeExpectation(tt.records)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("takeExpectation() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeDriverName is the database/sql driver name of fake databases, the DSN names the fakeDB
+const fakeDriverName = "testkit-fake"
+
+var (
+	registerFakeDriver sync.Once
+	fakeDatabases      sync.Map
+	fakeDatabaseCount  atomic.Int64
+)
+
+// fakeDB is an in-memory database/sql driver recording every statement it receives
+// Tests script its answers with onExec and onQuery, by default statements succeed and queries return no rows
+type fakeDB struct {
+	dsn string
+
+	mu sync.Mutex
+	// Answers statements with the number of affected rows, defaults to one row per VALUES tuple
+	onExec func(query string, args []any) (int64, error)
+	// Answers queries, defaults to no rows
+	onQuery func(query string, args []any) (*fakeRows, error)
+	// Fails the ping of new connections when set
+	pingErr error
+	// Statements and their arguments in execution order, transactions are logged as BEGIN, COMMIT and ROLLBACK
+	statements []fakeStatement
+}
+
+// fakeStatement is a statement or query received by a fakeDB
+type fakeStatement struct {
+	Query string
+	Args  []any
+}
+
+// fakeRows is the result of a query answered by onQuery
+type fakeRows struct {
+	columns []string
+	rows    [][]any
+	next    int
+}
+
+// newFakeDB opens a fake database that is closed when the test finishes
+func newFakeDB(t testing.TB) (*sql.DB, *fakeDB) {
+	t.Helper()
+
+	fake := newFakeDatabase()
+	db, err := sql.Open(fakeDriverName, fake.dsn)
+	if err != nil {
+		t.Fatalf("failed to open fake database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		fakeDatabases.Delete(fake.dsn)
+	})
+	return db, fake
+}
+
+// newFakeDatabase registers a fake database under a new DSN, for code that opens databases by driver name
+func newFakeDatabase() *fakeDB {
+	registerFakeDriver.Do(func() {
+		sql.Register(fakeDriverName, fakeDriver{})
+	})
+	fake := &fakeDB{dsn: fmt.Sprintf("fake-%d", fakeDatabaseCount.Add(1))}
+	fakeDatabases.Store(fake.dsn, fake)
+	return fake
+}
+
+// writeFixture writes a fixture file into a temporary directory of the test and returns its path
+func writeFixture(t testing.TB, name, content string) string {
+	t.Helper()
+	return writeFixtureIn(t, t.TempDir(), name, content)
+}
+
+// writeFixtureIn writes a fixture file into dir and returns its path
+func writeFixtureIn(t testing.TB, dir, name, content string) string {
+	t.Helper()
+
+	fixturePath := filepath.Join(dir, name)
+	if err := os.WriteFile(fixturePath, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write fixture %s: %v", name, err)
+	}
+	return fixturePath
+}
+
+// newFakeManager returns a fixture manager on a fake database
+func newFakeManager(t testing.TB, config *FixtureConfig) (*FixtureManager, *fakeDB) {
+	t.Helper()
+
+	db, fake := newFakeDB(t)
+	if config == nil {
+		config = DefaultFixtureConfig()
+	}
+	return NewFixtureManagerWithConfig(db, config), fake
+}
+
+// returnIDs answers queries like a database whose tables have an id column filled from one sequence
+// INSERTs return one id per VALUES tuple
+func (f *fakeDB) returnIDs() {
+	var ids atomic.Int64
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onQuery = func(query string, _ []any) (*fakeRows, error) {
+		if isColumnQuery(query) {
+			return columnRows(map[string]string{"id": "integer"}), nil
+		}
+		rows := &fakeRows{columns: []string{"id"}}
+		for range valueTuples(query) {
+			rows.rows = append(rows.rows, []any{ids.Add(1)})
+		}
+		return rows, nil
+	}
+}
+
+// queries returns the logged statements starting with prefix, in execution order
+func (f *fakeDB) queries(prefix string) []fakeStatement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var matching []fakeStatement
+	for _, statement := range f.statements {
+		if strings.HasPrefix(statement.Query, prefix) {
+			matching = append(matching, statement)
+		}
+	}
+	return matching
+}
+
+// queryTexts returns the text of the logged statements starting with prefix
+func (f *fakeDB) queryTexts(prefix string) []string {
+	var texts []string
+	for _, statement := range f.queries(prefix) {
+		texts = append(texts, statement.Query)
+	}
+	return texts
+}
+
+// reset forgets the logged statements
+func (f *fakeDB) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = nil
+}
+
+// record logs a statement
+func (f *fakeDB) record(query string, args []driver.NamedValue) {
+	values := namedValues(args)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.statements = append(f.statements, fakeStatement{Query: query, Args: values})
+}
+
+// exec answers a statement
+func (f *fakeDB) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	f.record(query, args)
+	f.mu.Lock()
+	onExec := f.onExec
+	f.mu.Unlock()
+
+	if onExec != nil {
+		affected, err := onExec(query, namedValues(args))
+		if err != nil {
+			return nil, err
+		}
+		return driver.RowsAffected(affected), nil
+	}
+	return driver.RowsAffected(valueTuples(query)), nil
+}
+
+// query answers a query
+func (f *fakeDB) query(query string, args []driver.NamedValue) (driver.Rows, error) {
+	f.record(query, args)
+	f.mu.Lock()
+	onQuery := f.onQuery
+	f.mu.Unlock()
+
+	if onQuery == nil {
+		return &fakeRows{}, nil
+	}
+	rows, err := onQuery(query, namedValues(args))
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		return &fakeRows{}, nil
+	}
+	// Every query reads the rows from the start
+	return &fakeRows{columns: rows.columns, rows: rows.rows}, nil
+}
+
+// namedValues returns the values of driver arguments
+func namedValues(args []driver.NamedValue) []any {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// valueTuples counts the VALUES tuples of an INSERT, other statements affect one row
+func valueTuples(query string) int64 {
+	_, values, ok := strings.Cut(query, " VALUES ")
+	if !ok {
+		return 1
+	}
+	depth, tuples := 0, int64(0)
+	for _, r := range values {
+		switch {
+		case r == '(':
+			if depth == 0 {
+				tuples++
+			}
+			depth++
+		case r == ')':
+			depth--
+		case depth == 0 && r != ',' && r != ' ':
+			// The tuples end where a trailing clause such as ON CONFLICT starts
+			return tuples
+		}
+	}
+	return tuples
+}
+
+// fakeDriver opens connections to the registered fake databases
+type fakeDriver struct{}
+
+// Open implements driver.Driver
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	value, ok := fakeDatabases.Load(dsn)
+	if !ok {
+		return nil, fmt.Errorf("no fake database %q", dsn)
+	}
+	return &fakeConn{db: value.(*fakeDB)}, nil
+}
+
+// fakeConn is a connection to a fakeDB
+type fakeConn struct {
+	db *fakeDB
+}
+
+var (
+	_ driver.ExecerContext      = (*fakeConn)(nil)
+	_ driver.QueryerContext     = (*fakeConn)(nil)
+	_ driver.ConnBeginTx        = (*fakeConn)(nil)
+	_ driver.Pinger             = (*fakeConn)(nil)
+	_ driver.NamedValueChecker  = (*fakeConn)(nil)
+	_ driver.SessionResetter    = (*fakeConn)(nil)
+	_ driver.ConnPrepareContext = (*fakeConn)(nil)
+)
+
+// Prepare implements driver.Conn, statements are always executed directly
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fake database does not prepare statements")
+}
+
+// PrepareContext implements driver.ConnPrepareContext
+func (c *fakeConn) PrepareContext(context.Context, string) (driver.Stmt, error) {
+	return nil, errors.New("fake database does not prepare statements")
+}
+
+// Close implements driver.Conn
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+// Begin implements driver.Conn
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx
+func (c *fakeConn) BeginTx(ctx context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	c.db.record("BEGIN", nil)
+	return fakeTx{db: c.db}, nil
+}
+
+// ExecContext implements driver.ExecerContext
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.db.exec(query, args)
+}
+
+// QueryContext implements driver.QueryerContext
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.db.query(query, args)
+}
+
+// Ping implements driver.Pinger
+func (c *fakeConn) Ping(context.Context) error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	return c.db.pingErr
+}
+
+// CheckNamedValue implements driver.NamedValueChecker, arguments are recorded as passed by the caller
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter
+func (c *fakeConn) ResetSession(context.Context) error {
+	return nil
+}
+
+// fakeTx is a transaction of a fakeDB, it only logs its end
+type fakeTx struct {
+	db *fakeDB
+}
+
+// Commit implements driver.Tx
+func (tx fakeTx) Commit() error {
+	tx.db.record("COMMIT", nil)
+	return nil
+}
+
+// Rollback implements driver.Tx
+func (tx fakeTx) Rollback() error {
+	tx.db.record("ROLLBACK", nil)
+	return nil
+}
+
+// Columns implements driver.Rows
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+// Close implements driver.Rows
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+// Next implements driver.Rows
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	for i, value := range r.rows[r.next] {
+		dest[i] = value
+	}
+	r.next++
+	return nil
+}
+
+// columnRows returns the information_schema.columns answer describing a table's columns by data type
+// Enums are given as "USER-DEFINED:<name>" and arrays as "ARRAY:<element udt>", e.g. "ARRAY:_int4"
+func columnRows(types map[string]string) *fakeRows {
+	rows := &fakeRows{columns: []string{"column_name", "data_type", "udt_schema", "udt_name"}}
+	for column, dataType := range types {
+		schema, udt := "pg_catalog", dataType
+		if name, ok := strings.CutPrefix(dataType, "USER-DEFINED:"); ok {
+			dataType, schema, udt = "USER-DEFINED", "public", name
+		}
+		if name, ok := strings.CutPrefix(dataType, "ARRAY:"); ok {
+			dataType, udt = "ARRAY", name
+		}
+		rows.rows = append(rows.rows, []any{column, dataType, schema, udt})
+	}
+	return rows
+}
+
+// isColumnQuery reports whether a query reads information_schema.columns
+func isColumnQuery(query string) bool {
+	return strings.Contains(query, "information_schema.columns")
+}
@@ -1,27 +1,131 @@
 package testkit
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log"
+	"math/rand/v2"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/legrch/logger"
 )
 
 // TableConfig holds configuration for a table's primary keys
 type TableConfig struct {
 	PrimaryKeys []string
+	// SQL statements executed after the table's rows are inserted
+	AfterLoad []string
+	// Timestamp column used to also delete rows created after the manager started
+	CreatedAtColumn string
+	// Tables whose rows must be inserted before this table's rows
+	DependsOn []string
+	// Type hints of columns holding maps or lists: "json", "jsonb" or "array"
+	ColumnTypes map[string]string
 }
 
+// NowBinding controls how the NOW() fixture value is bound
+type NowBinding int
+
+const (
+	// NowClientTime binds the client's time.Now() as a parameter (default)
+	NowClientTime NowBinding = iota
+	// NowServerTime inlines the database's current timestamp expression so values reflect database time
+	// The expression follows the dialect, e.g. datetime('now') on SQLite
+	NowServerTime
+)
+
+// LargeTableCleanup selects how tables above FixtureConfig.LargeTableThreshold are cleaned up
+type LargeTableCleanup int
+
+const (
+	// LargeTableTruncate truncates the table, which also removes rows that fixtures did not insert (default)
+	LargeTableTruncate LargeTableCleanup = iota
+	// LargeTableCreatedAt deletes rows created after the manager started
+	// The table must be configured with ConfigureCreatedAtCleanup
+	LargeTableCreatedAt
+)
+
 // FixtureConfig holds configuration for fixture loading
 type FixtureConfig struct {
-	// File extensions to consider as fixtures (defaults to [".yml", ".yaml"])
+	// File extensions to consider as fixtures (defaults to [".yml", ".yaml"]), add ".json" or ".csv" to load JSON or CSV fixtures
 	FileExtensions []string
+	// Parsers by file extension (e.g. ".toml"), extensions without a parser are parsed as YAML, or JSON for ".json"
+	Parsers map[string]FixtureParser
+	// Introspect column types and cast bound values accordingly (uuid, json, jsonb, enums, arrays)
+	// This adds one catalog query per table, cached for the lifetime of the manager
+	TypeAwareBinding bool
+	// Maps fixture keys to database column names, e.g. CamelToSnake (defaults to no mapping)
+	ColumnNameMapper func(string) string
+	// Commit after each table instead of once per file
+	// This shortens lock duration but a failing table leaves the tables committed before it in place
+	CommitPerTable bool
+	// Load at most this many rows per table, 0 means unlimited
+	// Truncating can break referential integrity when a referenced parent row is cut
+	MaxRowsPerTable int
+	// How NOW() values are bound (defaults to NowClientTime)
+	NowBinding NowBinding
+	// Per-statement timeout for fixture loading and cleanup, 0 disables it
+	// Applied with SET LOCAL statement_timeout, so it is PostgreSQL specific
+	StatementTimeout time.Duration
+	// Stop tracking primary keys of a table once it has more rows than this, 0 disables the limit
+	// Such tables are cleaned up with LargeTableCleanup instead, keeping memory and cleanup queries bounded
+	LargeTableThreshold int
+	// Cleanup strategy for tables above LargeTableThreshold (defaults to LargeTableTruncate)
+	LargeTableCleanup LargeTableCleanup
+	// Randomize the order in which files and tables are loaded to surface hidden ordering assumptions
+	ShuffleOrder bool
+	// Seed for ShuffleOrder, 0 picks a random seed which is logged so failures can be reproduced
+	ShuffleSeed int64
+	// Seed for generated fixture values such as !faker and !uuid, 0 picks a random seed which is logged
+	GeneratorSeed int64
+	// Expand ${VAR} references in string values from the environment
+	ExpandEnv bool
+	// What to do when a referenced variable or template key is undefined (defaults to MissingVarError)
+	OnMissingVar MissingVarMode
+	// Suffix of overlay files merged onto their base file, e.g. "local" merges users.local.yml onto users.yml
+	// Overlay files are not loaded on their own by LoadFixturesFromDir
+	OverlaySuffix string
+	// Order cleanup by the foreign keys of the cleaned up tables, deleting parents first where the key cascades
+	// PostgreSQL only, the foreign keys are queried once and cached
+	CascadeAwareCleanup bool
+	// Disable user triggers on the cleaned up tables while cleanup deletes their rows
+	// PostgreSQL only, requires ownership of the tables (ALTER TABLE ... DISABLE TRIGGER USER)
+	DisableTriggersOnCleanup bool
+	// Load up to this many tables of a fixture set concurrently, 0 or 1 loads them sequentially
+	// Each table is inserted and committed in its own transaction, as with CommitPerTable
+	// A table waits for the tables it depends on, see ConfigureTableDependencies
+	ParallelWorkers int
+	// Only warn when a LoadFixturesGlob pattern matches no fixture file instead of failing
+	AllowEmptyGlob bool
+	// SQL dialect of generated queries (defaults to DialectPostgres)
+	Dialect Dialect
+	// Fail the load when an INSERT does not affect exactly one row, e.g. a row silently skipped by a conflict clause
+	// With BatchSize every row of a batch must be inserted
+	StrictInsert bool
+	// Handling of rows whose primary key already exists, e.g. left behind by an interrupted run (defaults to ConflictFail)
+	ConflictMode ConflictMode
+	// Log the statements of fixture loading and cleanup with their arguments instead of executing them
+	// Transactions still begin and are rolled back, keys written in the fixtures are tracked for the logged cleanup
+	DryRun bool
+	// Insert up to this many consecutive rows of a table with the same columns in one multi-row INSERT,
+	// 0 or 1 inserts rows one by one; rows with an _alias are always inserted on their own
+	BatchSize int
+	// String value bound as SQL NULL, e.g. `\N` or "<null>" in spreadsheet exports, empty disables it
+	// Only whole top-level string values match, so pick a sentinel that cannot occur in real data
+	NullSentinel string
+	// Delete at most this many tracked rows of a table per DELETE statement during cleanup (defaults to 1000)
+	// All statements run in the cleanup transaction, so a failing chunk still rolls back the whole cleanup
+	DeleteBatchSize int
 }
 
 // DefaultFixtureConfig returns the default fixture configuration
@@ -38,7 +142,83 @@ type FixtureManager struct {
 	// Map of table name to its configuration for non-standard primary keys
 	tableConfigs map[string]TableConfig
 	// Track inserted records by table and their primary key values
-	insertedRecords map[string][]map[string]any
+	insertedRecords map[string][]trackedRecord
+	// Tables that received rows from a fixture load
+	loadedTables map[string]struct{}
+	// Tables above the large table threshold whose primary keys are no longer tracked
+	largeTables map[string]struct{}
+	// Cached column types by table, used by type-aware binding
+	columnTypes map[string]map[string]columnType
+	// Time the manager was created, rows created after it are removed by created-at cleanup
+	startTime time.Time
+	// Random source for ShuffleOrder, created on first use
+	shuffler *rand.Rand
+	// Seed and per-table random sources of generated values, created on first use
+	generatorSeed int64
+	generators    map[string]*rand.Rand
+	// Foreign keys of the database, loaded on first use by CascadeAwareCleanup
+	foreignKeys []foreignKey
+	// Tables referenced through aliases by each table, inferred from loaded fixtures
+	referencedTables map[string][]string
+	// Tables in the order rows were first inserted into them, cleanup deletes independent tables in reverse
+	loadOrder []string
+	// Unique ID of the manager, the value of !runid
+	runID string
+	// Data fixture files are rendered with, nil disables templating, see SetTemplateData
+	templateData map[string]any
+	// Decrypts !secret values, see RegisterDecryptor
+	decryptor func(ciphertext string) (string, error)
+	// Functions producing the values of fixture tokens, see RegisterValueFunc
+	valueFuncs map[string]ValueFunc
+	// Tables recorded by Snapshot in restore order, see Restore
+	snapshotTables []string
+	// Guards the tracking maps and the column type cache during parallel loads
+	mu sync.Mutex
+}
+
+// trackedRecord is a row inserted from a fixture, identified by its primary key values
+type trackedRecord struct {
+	Keys map[string]any
+	// Fixture file and row index within its table, reported when cleanup fails
+	Source string
+	Index  int
+}
+
+// String describes where the tracked row came from
+func (r trackedRecord) String() string {
+	return fmt.Sprintf("%s[%d] %v", r.Source, r.Index, r.Keys)
+}
+
+// loadState is shared by the transactions of one fixture load
+type loadState struct {
+	aliases *aliasRegistry
+	// Dependencies that only hold for this load, the order of an ordered fixture file
+	dependencies map[string][]string
+	// Receives the returned values of each inserted row, nil when rows are not observed
+	onInsert func(table string, returned map[string]any)
+	// Collects the rows of the load once committed, nil when they are not needed
+	committed map[string][]trackedRecord
+	// Serializes onInsert calls and committed updates from parallel loads
+	mu sync.Mutex
+}
+
+// inserted passes a returned row to the onInsert callback
+func (l *loadState) inserted(tableName string, returned map[string]any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onInsert(tableName, returned)
+}
+
+// commit collects the rows of a committed transaction when the load keeps them
+func (l *loadState) commit(pending map[string][]trackedRecord) {
+	if l.committed == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for tableName, records := range pending {
+		l.committed[tableName] = append(l.committed[tableName], records...)
+	}
 }
 
 // TableFixtures represents fixtures for all tables
@@ -52,25 +232,63 @@ func NewFixtureManager(db *sql.DB) *FixtureManager {
 // NewFixtureManagerWithConfig creates a new fixture manager with the given configuration
 func NewFixtureManagerWithConfig(db *sql.DB, config *FixtureConfig) *FixtureManager {
 	return &FixtureManager{
-		db:              db,
-		config:          config,
-		tableConfigs:    make(map[string]TableConfig),
-		insertedRecords: make(map[string][]map[string]any),
+		db:               db,
+		config:           config,
+		tableConfigs:     make(map[string]TableConfig),
+		insertedRecords:  make(map[string][]trackedRecord),
+		loadedTables:     make(map[string]struct{}),
+		largeTables:      make(map[string]struct{}),
+		columnTypes:      make(map[string]map[string]columnType),
+		referencedTables: make(map[string][]string),
+		startTime:        time.Now(),
+		runID:            newUUID(),
 	}
 }
 
 // ConfigureTable sets custom primary key configuration for a table
 // Only needed when the primary key is not 'id'
 func (fm *FixtureManager) ConfigureTable(tableName string, primaryKeys []string) {
-	fm.tableConfigs[tableName] = TableConfig{
-		PrimaryKeys: primaryKeys,
-	}
+	config := fm.tableConfigs[tableName]
+	config.PrimaryKeys = primaryKeys
+	fm.tableConfigs[tableName] = config
+}
+
+// ConfigureAfterLoad registers SQL statements to run after a table's rows are inserted
+// The statements run inside the load transaction, e.g. to refresh a materialized view
+func (fm *FixtureManager) ConfigureAfterLoad(tableName string, statements ...string) {
+	config := fm.tableConfigs[tableName]
+	config.AfterLoad = append(config.AfterLoad, statements...)
+	fm.tableConfigs[tableName] = config
+}
+
+// ConfigureCreatedAtCleanup makes cleanup also delete the table's rows whose column is after the manager's start time
+// This removes rows created by the application under test that were never tracked
+// The column must reliably hold the creation time of each row, compared against the test process clock
+func (fm *FixtureManager) ConfigureCreatedAtCleanup(tableName, column string) {
+	config := fm.tableConfigs[tableName]
+	config.CreatedAtColumn = column
+	fm.tableConfigs[tableName] = config
+}
+
+// ConfigureTableDependencies declares the tables whose rows must be inserted before the table's rows
+func (fm *FixtureManager) ConfigureTableDependencies(tableName string, dependsOn []string) {
+	config := fm.tableConfigs[tableName]
+	config.DependsOn = dependsOn
+	fm.tableConfigs[tableName] = config
+}
+
+// ConfigureColumnTypes sets how maps and lists bound to the table's columns are encoded, by column name
+// "json" and "jsonb" encode the value as JSON and "array" with pq.Array, see TypeAwareBinding to introspect instead
+func (fm *FixtureManager) ConfigureColumnTypes(tableName string, hints map[string]string) {
+	config := fm.tableConfigs[tableName]
+	config.ColumnTypes = hints
+	fm.tableConfigs[tableName] = config
 }
 
 // getPrimaryKeys returns the primary keys for a table
 // Uses 'id' by default unless configured otherwise
 func (fm *FixtureManager) getPrimaryKeys(tableName string) []string {
-	if config, ok := fm.tableConfigs[tableName]; ok {
+	if config, ok := fm.tableConfigs[tableName]; ok && len(config.PrimaryKeys) > 0 {
 		return config.PrimaryKeys
 	}
 	return []string{"id"}
@@ -78,18 +296,146 @@ func (fm *FixtureManager) getPrimaryKeys(tableName string) []string {
 
 // LoadYAMLFixtures loads fixtures from a YAML file
 func (fm *FixtureManager) LoadYAMLFixtures(fixturePath string) error {
-	content, err := os.ReadFile(fixturePath)
+	return fm.LoadYAMLFixturesContext(context.Background(), fixturePath)
+}
+
+// LoadYAMLFixturesContext loads fixtures from a YAML file, cancelling the load when ctx is done
+func (fm *FixtureManager) LoadYAMLFixturesContext(ctx context.Context, fixturePath string) error {
+	fsys, name := osFile(fixturePath)
+	return fm.LoadYAMLFixturesFSContext(ctx, fsys, name)
+}
+
+// LoadYAMLFixturesWithCallback loads fixtures from a YAML file, passing every inserted row to onInsert
+// The row holds the values returned by the database (INSERT ... RETURNING *, PostgreSQL only), including generated
+// keys and defaults. onInsert runs as rows are inserted, before their transaction commits, and may be nil
+func (fm *FixtureManager) LoadYAMLFixturesWithCallback(
+	fixturePath string, onInsert func(table string, returned map[string]any),
+) error {
+	fsys, name := osFile(fixturePath)
+	fixtures, order, err := fm.readFixtures(fsys, name, YAMLParser{})
+	if err != nil {
+		return err
+	}
+
+	return fm.loadFixtures(context.Background(), fixturePath, fixtures, order, onInsert)
+}
+
+// LoadFixtureFile loads fixtures from a file using the parser registered for its extension
+func (fm *FixtureManager) LoadFixtureFile(fixturePath string) error {
+	return fm.LoadFixtureFileContext(context.Background(), fixturePath)
+}
+
+// LoadFixtureFileContext loads fixtures from a file like LoadFixtureFile, cancelling the load when ctx is done
+func (fm *FixtureManager) LoadFixtureFileContext(ctx context.Context, fixturePath string) error {
+	fsys, name := osFile(fixturePath)
+	return fm.loadFile(ctx, fsys, name, fm.parserFor(path.Ext(name)))
+}
+
+// loadFile reads and parses a fixture file, then inserts its fixtures
+func (fm *FixtureManager) loadFile(ctx context.Context, fsys fs.FS, name string, parser FixtureParser) error {
+	fixtures, order, err := fm.readFixtures(fsys, name, parser)
+	if err != nil {
+		return err
+	}
+
+	return fm.loadFixtures(ctx, sourceName(fsys, name), fixtures, order, nil)
+}
+
+// readFixtures reads and parses a fixture file, expanding variables when enabled
+// The order lists the tables of an ordered fixture file, it is nil for other files
+func (fm *FixtureManager) readFixtures(
+	fsys fs.FS, name string, parser FixtureParser,
+) (TableFixtures, []string, error) {
+	fixturePath := sourceName(fsys, name)
+	content, err := fs.ReadFile(fsys, name)
 	if err != nil {
-		return fmt.Errorf("failed to read fixture file: %w", err)
+		return nil, nil, fmt.Errorf("failed to read fixture file %s: %w", fixturePath, err)
+	}
+	if content, err = fm.renderTemplate(fixturePath, content); err != nil {
+		return nil, nil, err
 	}
 
 	var fixtures TableFixtures
-	if err2 := yaml.Unmarshal(content, &fixtures); err2 != nil {
-		return fmt.Errorf("failed to unmarshal YAML fixtures: %w", err2)
+	var order []string
+	switch p := parser.(type) {
+	case orderedParser:
+		fixtures, order, err = p.parseOrdered(content)
+	case fileParser:
+		fixtures, err = p.parseFile(name, content)
+	default:
+		fixtures, err = parser.Parse(content)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid fixture file %s: %w", fixturePath, err)
+	}
+
+	if fm.config.ExpandEnv {
+		if err := fm.expandFixtureVars(fixtures); err != nil {
+			return nil, nil, fmt.Errorf("failed to expand variables in fixture file %s: %w", fixturePath, err)
+		}
+	}
+
+	return fixtures, order, nil
+}
+
+// parserFor returns the parser registered for a file extension, defaulting to JSON for .json, CSV for .csv
+// and YAML otherwise
+func (fm *FixtureManager) parserFor(ext string) FixtureParser {
+	if parser, ok := fm.config.Parsers[ext]; ok {
+		return parser
+	}
+	switch ext {
+	case ".json":
+		return JSONParser{}
+	case ".csv":
+		return CSVParser{NullSentinel: fm.config.NullSentinel}
+	default:
+		return YAMLParser{}
+	}
+}
+
+// loadFixtures inserts parsed fixtures, in a single transaction unless CommitPerTable is set
+// The source names the fixture file the rows came from
+// The order of an ordered fixture file only applies to this load, onInsert, when not nil, receives the values
+// returned for every inserted row
+func (fm *FixtureManager) loadFixtures(
+	ctx context.Context, source string, fixtures TableFixtures, order []string,
+	onInsert func(table string, returned map[string]any),
+) error {
+	// Aliases are resolved within one load, across its transactions
+	load := &loadState{aliases: newAliasRegistry(), dependencies: orderDependencies(order), onInsert: onInsert}
+	return fm.runLoad(ctx, source, fixtures, load)
+}
+
+// runLoad inserts parsed fixtures with the given load state
+func (fm *FixtureManager) runLoad(ctx context.Context, source string, fixtures TableFixtures, load *loadState) error {
+	tableNames, err := fm.tableOrder(fixtures, load.dependencies)
+	if err != nil {
+		return err
+	}
+
+	if fm.config.ParallelWorkers > 1 {
+		return fm.loadTablesParallel(ctx, source, fixtures, tableNames, load)
+	}
+
+	if fm.config.CommitPerTable {
+		for _, tableName := range tableNames {
+			if err := fm.loadTables(ctx, source, fixtures, []string{tableName}, load); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
 
+	return fm.loadTables(ctx, source, fixtures, tableNames, load)
+}
+
+// loadTables inserts the given tables of the fixtures within one transaction
+func (fm *FixtureManager) loadTables(
+	ctx context.Context, source string, fixtures TableFixtures, tableNames []string, load *loadState,
+) error {
 	// Begin transaction
-	tx, err := fm.db.Begin()
+	tx, err := fm.begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
@@ -99,91 +445,281 @@ func (fm *FixtureManager) LoadYAMLFixtures(fixturePath string) error {
 		}
 	}()
 
-	// Process each table
-	for tableName, records := range fixtures {
-		if err := fm.insertRecords(tx, tableName, records); err != nil {
-			return fmt.Errorf("failed to insert records for table %s: %w", tableName, err)
-		}
+	// Rows are only tracked once the transaction commits, a rolled back load leaves nothing behind
+	pending := make(map[string][]trackedRecord)
+	if err := fm.insertTables(ctx, tx, source, fixtures, tableNames, pending, load); err != nil {
+		return err
 	}
 
 	// Commit transaction
-	if err := tx.Commit(); err != nil {
+	if err := fm.commit(tx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	fm.track(pending)
+	load.commit(pending)
 
 	return nil
 }
 
-// insertRecords inserts records for a specific table
-func (fm *FixtureManager) insertRecords(tx *sql.Tx, tableName string, records []map[string]any) error {
-	for _, record := range records {
-		// Extract columns and values
-		var columns []string
-		var placeholders []string
-		var values []any
-		i := 1
-
-		// Track primary key values for cleanup
-		pkValues := make(map[string]any)
-		primaryKeys := fm.getPrimaryKeys(tableName)
-		for _, pk := range primaryKeys {
-			if value, exists := record[pk]; exists {
-				pkValues[pk] = value
-			}
+// tableOrder returns the order in which the tables of the fixtures are inserted
+// Declared dependencies and tables referenced by alias are inserted first, shuffling only reorders tables independent of each other
+// extra adds dependencies of this load only, see tableDependencies
+func (fm *FixtureManager) tableOrder(fixtures TableFixtures, extra map[string][]string) ([]string, error) {
+	tableNames := make([]string, 0, len(fixtures))
+	for tableName := range fixtures {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+	shuffleSlice(fm.shuffleSource(), tableNames)
+	fm.inferDependencies(fixtures)
+	return fm.dependencyOrder(tableNames, extra)
+}
+
+// insertTables inserts the given tables of the fixtures using tx
+func (fm *FixtureManager) insertTables(
+	ctx context.Context, tx *sql.Tx, source string, fixtures TableFixtures, tableNames []string,
+	pending map[string][]trackedRecord, load *loadState,
+) error {
+	for _, tableName := range tableNames {
+		records, expected, err := takeExpectation(fixtures[tableName])
+		if err != nil {
+			return fmt.Errorf("invalid fixtures for table %s: %w", tableName, err)
+		}
+		if records, err = fm.expandGenerators(tableName, records); err != nil {
+			return fmt.Errorf("invalid fixtures for table %s: %w", tableName, err)
+		}
+		if limit := fm.config.MaxRowsPerTable; limit > 0 && len(records) > limit {
+			records = records[:limit]
+		}
+		fm.recordLoadOrder(tableName)
+		inserted, err := fm.insertRecords(ctx, tx, source, tableName, records, pending, load)
+		if err != nil {
+			return fmt.Errorf("failed to insert records for table %s: %w", tableName, err)
 		}
+		if expected != nil && inserted != *expected {
+			return fmt.Errorf("table %s from %s expects %d rows but %d were inserted", tableName, source, *expected, inserted)
+		}
+		if err := fm.runAfterLoad(ctx, tx, tableName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		// Store primary key values for cleanup
-		if len(pkValues) > 0 {
-			if _, exists := fm.insertedRecords[tableName]; !exists {
-				fm.insertedRecords[tableName] = make([]map[string]any, 0)
-			}
-			fm.insertedRecords[tableName] = append(fm.insertedRecords[tableName], pkValues)
+// shuffleSource returns the random source used to shuffle load order, or nil when ShuffleOrder is disabled
+func (fm *FixtureManager) shuffleSource() *rand.Rand {
+	if !fm.config.ShuffleOrder {
+		return nil
+	}
+	if fm.shuffler == nil {
+		seed := fm.config.ShuffleSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
 		}
+		logger.Info("Shuffling fixture load order", "seed", seed)
+		fm.shuffler = rand.New(rand.NewPCG(uint64(seed), 0)) //nolint:gosec // G404: not used for security
+	}
+	return fm.shuffler
+}
+
+// shuffleSlice randomizes the order of items in place, doing nothing when r is nil
+func shuffleSlice[T any](r *rand.Rand, items []T) {
+	if r == nil {
+		return
+	}
+	r.Shuffle(len(items), func(i, j int) {
+		items[i], items[j] = items[j], items[i]
+	})
+}
 
-		for column, value := range record {
-			columns = append(columns, column)
-			placeholders = append(placeholders, fmt.Sprintf("$%d", i))
+// begin starts a transaction with the configured session settings applied
+func (fm *FixtureManager) begin(ctx context.Context) (*sql.Tx, error) {
+	tx, err := fm.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
 
-			// Handle special values
-			switch v := value.(type) {
-			case string:
-				if v == "NOW()" {
-					values = append(values, time.Now())
-				} else {
-					values = append(values, v)
-				}
-			default:
-				values = append(values, v)
+	if timeout := fm.config.StatementTimeout; timeout > 0 {
+		// SET does not accept parameters, the value is a plain integer so formatting is safe
+		query := fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				log.Printf("failed to rollback transaction: %v", rollbackErr)
 			}
-			i++
+			return nil, fmt.Errorf("failed to set statement timeout: %w", err)
 		}
+	}
 
-		// Build and execute query
-		// This is safe because we're using quoted identifiers and parameterized values
-		//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
-		query := fmt.Sprintf(
-			"INSERT INTO %s (%s) VALUES (%s)",
-			tableName,
-			strings.Join(columns, ", "),
-			strings.Join(placeholders, ", "),
-		)
+	return tx, nil
+}
+
+// track records the primary keys of committed rows so CleanupFixtures can remove them
+func (fm *FixtureManager) track(pending map[string][]trackedRecord) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
 
-		if _, err := tx.Exec(query, values...); err != nil {
-			return fmt.Errorf("failed to insert record: %w", err)
+	for tableName, keys := range pending {
+		fm.loadedTables[tableName] = struct{}{}
+		if _, large := fm.largeTables[tableName]; large || len(keys) == 0 {
+			continue
+		}
+
+		fm.insertedRecords[tableName] = append(fm.insertedRecords[tableName], keys...)
+		if threshold := fm.config.LargeTableThreshold; threshold > 0 && len(fm.insertedRecords[tableName]) > threshold {
+			// Switch to bulk cleanup and release the tracked keys
+			fm.largeTables[tableName] = struct{}{}
+			delete(fm.insertedRecords, tableName)
 		}
 	}
+}
+
+// GetInsertedKeys returns the primary key values of the rows loaded into a table, including generated ones
+// Generated keys are only known with DialectPostgres for tables with an id column or configured primary keys,
+// tables above LargeTableThreshold return nil
+func (fm *FixtureManager) GetInsertedKeys(tableName string) []map[string]any {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	records := fm.insertedRecords[tableName]
+	if len(records) == 0 {
+		return nil
+	}
+	keys := make([]map[string]any, 0, len(records))
+	for _, record := range records {
+		keys = append(keys, copyRecord(record.Keys))
+	}
+	return keys
+}
+
+// isLargeTable reports whether the table is above the large table threshold
+func (fm *FixtureManager) isLargeTable(tableName string) bool {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	_, large := fm.largeTables[tableName]
+	return large
+}
+
+// insertRecords inserts records for a specific table
+// Primary key values of the inserted rows are collected into pending, it returns the number of inserted rows
+func (fm *FixtureManager) insertRecords(
+	ctx context.Context, tx *sql.Tx, source, tableName string, records []map[string]any,
+	pending map[string][]trackedRecord, load *loadState,
+) (int64, error) {
+	// Register the table even when its rows have no primary key values to track
+	if _, exists := pending[tableName]; !exists && len(records) > 0 {
+		pending[tableName] = nil
+	}
 
+	var types map[string]columnType
+	if fm.config.TypeAwareBinding {
+		var err error
+		if types, err = fm.getColumnTypes(ctx, tx, tableName); err != nil {
+			return 0, err
+		}
+	}
+	hints := fm.tableConfigs[tableName].ColumnTypes
+	var inserted int64
+	var batch []preparedRow
+	var batchParams int
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := fm.insertBatch(ctx, tx, source, tableName, batch, pending, load)
+		inserted += n
+		batch, batchParams = batch[:0], 0
+		return err
+	}
+
+	for index, record := range records {
+		// The alias names the row for references from later rows, it is not a column
+		alias, record := takeAlias(record)
+
+		// Primary key tracking and binding both use the mapped column names
+		record = mapColumnNames(record, fm.config.ColumnNameMapper)
+
+		// Substitute values of previously inserted rows, then values of other columns of the same row
+		var err error
+		if record, err = load.aliases.resolveReferences(record, fm.config.ColumnNameMapper); err != nil {
+			return 0, fmt.Errorf("table %s row %d: %w", tableName, index, err)
+		}
+		if record, err = resolveSelfReferences(record, fm.config.ColumnNameMapper); err != nil {
+			return 0, fmt.Errorf("table %s row %d: %w", tableName, index, err)
+		}
+		if record, err = fm.resolveDeferredValues(record); err != nil {
+			return 0, fmt.Errorf("table %s row %d: %w", tableName, index, err)
+		}
+
+		row, err := fm.prepareRow(index, alias, record, types, hints)
+		if err != nil {
+			return 0, err
+		}
+
+		// Aliased rows are inserted on their own so their returned values are known before later rows resolve
+		if len(batch) > 0 && (alias != "" || !slices.Equal(batch[0].columns, row.columns) ||
+			batchParams+row.params() > maxBatchParameters) {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+		batch = append(batch, row)
+		batchParams += row.params()
+		if alias != "" || len(batch) >= max(fm.config.BatchSize, 1) {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	return inserted, nil
+}
+
+// nowValue returns the value bound for NOW() according to the configured binding
+func (fm *FixtureManager) nowValue() any {
+	if fm.config.NowBinding == NowServerTime {
+		return sqlExpression(fm.config.Dialect.nowExpression(0))
+	}
+	return time.Now()
+}
+
+// runAfterLoad executes the statements registered for a table after its rows are inserted
+func (fm *FixtureManager) runAfterLoad(ctx context.Context, tx *sql.Tx, tableName string) error {
+	for _, statement := range fm.tableConfigs[tableName].AfterLoad {
+		if err := fm.exec(ctx, tx, statement); err != nil {
+			return fmt.Errorf("failed to run after-load statement for table %s: %w", tableName, err)
+		}
+	}
 	return nil
 }
 
 // CleanupFixtures removes test data from the database
 func (fm *FixtureManager) CleanupFixtures() error {
-	if len(fm.insertedRecords) == 0 {
+	return fm.CleanupFixturesContext(context.Background())
+}
+
+// CleanupFixturesContext removes test data like CleanupFixtures, cancelling the cleanup when ctx is done
+// A cancelled cleanup rolls back, so the rows stay tracked and a later call can retry
+func (fm *FixtureManager) CleanupFixturesContext(ctx context.Context) error {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	var createdAtTables []string
+	for tableName, config := range fm.tableConfigs {
+		if config.CreatedAtColumn != "" {
+			createdAtTables = append(createdAtTables, tableName)
+		}
+	}
+	sort.Strings(createdAtTables)
+
+	if len(fm.insertedRecords) == 0 && len(createdAtTables) == 0 && len(fm.largeTables) == 0 {
 		return nil // Nothing to clean up
 	}
 
 	// Begin transaction
-	tx, err := fm.db.Begin()
+	tx, err := fm.begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin cleanup transaction: %w", err)
 	}
@@ -193,26 +729,117 @@ func (fm *FixtureManager) CleanupFixtures() error {
 		}
 	}()
 
-	// Clean up each table's inserted records
-	for tableName, records := range fm.insertedRecords {
+	if createdAtTables, err = fm.cleanupOrder(ctx, tx, createdAtTables); err != nil {
+		return err
+	}
+
+	// Tables whose triggers are disabled until cleanup re-enables them
+	var disabledTriggers []string
+	if fm.config.DisableTriggersOnCleanup {
+		disabledTriggers = fm.cleanupTableNames(createdAtTables)
+		defer func() {
+			if disabledTriggers == nil {
+				return
+			}
+			// After a failed statement the transaction is aborted, rolling it back restores the triggers too
+			if err := fm.setTriggers(ctx, tx, disabledTriggers, true); err != nil {
+				log.Printf("failed to re-enable triggers, relying on rollback: %v", err)
+			}
+		}()
+		if err := fm.setTriggers(ctx, tx, disabledTriggers, false); err != nil {
+			return err
+		}
+	}
+
+	// Clean up each table's inserted records, dependent tables before the tables they reference
+	trackedTables := make([]string, 0, len(fm.insertedRecords))
+	for tableName := range fm.insertedRecords {
+		trackedTables = append(trackedTables, tableName)
+	}
+	sort.Strings(trackedTables)
+	if trackedTables, err = fm.cleanupOrder(ctx, tx, trackedTables); err != nil {
+		return err
+	}
+	for _, tableName := range trackedTables {
+		records := fm.insertedRecords[tableName]
 		if len(records) == 0 {
 			continue
 		}
 
-		primaryKeys := fm.getPrimaryKeys(tableName)
+		if err := fm.deleteRecords(ctx, tx, tableName, records); err != nil {
+			return err
+		}
+	}
+
+	// Clean up tables that exceeded the tracking threshold
+	if err := fm.cleanupLargeTables(ctx, tx); err != nil {
+		return err
+	}
+
+	// Remove rows created since the manager started, including those inserted by the application
+	for _, tableName := range createdAtTables {
+		//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+		query := fmt.Sprintf(
+			"DELETE FROM %s WHERE %s > %s",
+			fm.config.Dialect.QuoteIdentifier(tableName),
+			fm.config.Dialect.QuoteIdentifier(fm.tableConfigs[tableName].CreatedAtColumn),
+			fm.config.Dialect.Placeholder(1),
+		)
+		if err := fm.exec(ctx, tx, query, fm.startTime); err != nil {
+			return fmt.Errorf("failed to cleanup rows created in table %s: %w", tableName, err)
+		}
+	}
+
+	if disabledTriggers != nil {
+		if err := fm.setTriggers(ctx, tx, disabledTriggers, true); err != nil {
+			return err
+		}
+		disabledTriggers = nil
+	}
+
+	// Commit transaction
+	if err := fm.commit(tx); err != nil {
+		return fmt.Errorf("failed to commit cleanup transaction: %w", err)
+	}
+
+	// Clear the tracking map after successful cleanup
+	fm.insertedRecords = make(map[string][]trackedRecord)
+	fm.largeTables = make(map[string]struct{})
+
+	return nil
+}
+
+// defaultDeleteBatchSize is the number of rows deleted per statement when DeleteBatchSize is not set
+const defaultDeleteBatchSize = 1000
+
+// deleteRecords deletes tracked rows of a table by their primary keys, rows without known keys are skipped
+// Rows are deleted in chunks of DeleteBatchSize, each chunk also staying below the bind parameter limit
+func (fm *FixtureManager) deleteRecords(
+	ctx context.Context, tx *sql.Tx, tableName string, records []trackedRecord,
+) error {
+	primaryKeys := fm.getPrimaryKeys(tableName)
+	chunkSize := fm.config.DeleteBatchSize
+	if chunkSize <= 0 {
+		chunkSize = defaultDeleteBatchSize
+	}
+	chunkSize = min(chunkSize, maxBatchParameters/len(primaryKeys))
+
+	for start := 0; start < len(records); start += chunkSize {
+		chunk := records[start:min(start+chunkSize, len(records))]
 
 		// Build WHERE clause for composite keys
 		var conditions []string
 		var values []any
 		paramCount := 1
 
-		for _, record := range records {
+		for _, record := range chunk {
 			var recordConditions []string
 			var recordValues []any
 
 			for _, pk := range primaryKeys {
-				if value, exists := record[pk]; exists {
-					recordConditions = append(recordConditions, fmt.Sprintf("%s = $%d", pk, paramCount))
+				if value, exists := record.Keys[pk]; exists {
+					recordConditions = append(recordConditions,
+						fm.config.Dialect.QuoteIdentifier(pk)+" = "+fm.config.Dialect.Placeholder(paramCount))
 					recordValues = append(recordValues, value)
 					paramCount++
 				}
@@ -224,55 +851,164 @@ func (fm *FixtureManager) CleanupFixtures() error {
 			}
 		}
 
-		if len(conditions) > 0 {
-			// Build and execute delete query
-			// This is safe because we're using quoted identifiers and parameterized values
-			//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
-			query := fmt.Sprintf(
-				"DELETE FROM %s WHERE %s",
-				tableName,
-				strings.Join(conditions, " OR "),
-			)
+		if len(conditions) == 0 {
+			continue
+		}
 
-			if _, err := tx.Exec(query, values...); err != nil {
-				return fmt.Errorf("failed to cleanup table %s: %w", tableName, err)
-			}
+		// Build and execute delete query
+		// This is safe because we're using quoted identifiers and parameterized values
+		//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+		query := fmt.Sprintf(
+			"DELETE FROM %s WHERE %s",
+			fm.config.Dialect.QuoteIdentifier(tableName),
+			strings.Join(conditions, " OR "),
+		)
+
+		if err := fm.exec(ctx, tx, query, values...); err != nil {
+			return fmt.Errorf("failed to cleanup table %s (%s): %w", tableName, describeRecords(chunk), err)
 		}
 	}
+	return nil
+}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit cleanup transaction: %w", err)
+// cleanupTableNames returns the sorted names of every table cleanup touches
+func (fm *FixtureManager) cleanupTableNames(createdAtTables []string) []string {
+	set := make(map[string]struct{})
+	for tableName := range fm.insertedRecords {
+		set[tableName] = struct{}{}
+	}
+	for tableName := range fm.largeTables {
+		set[tableName] = struct{}{}
+	}
+	for _, tableName := range createdAtTables {
+		set[tableName] = struct{}{}
 	}
 
-	// Clear the tracking map after successful cleanup
-	fm.insertedRecords = make(map[string][]map[string]any)
+	tables := make([]string, 0, len(set))
+	for tableName := range set {
+		tables = append(tables, tableName)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// setTriggers enables or disables user triggers on the tables
+func (fm *FixtureManager) setTriggers(ctx context.Context, tx *sql.Tx, tables []string, enable bool) error {
+	action := "DISABLE"
+	if enable {
+		action = "ENABLE"
+	}
 
+	for _, tableName := range tables {
+		//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+		query := fmt.Sprintf("ALTER TABLE %s %s TRIGGER USER", fm.config.Dialect.QuoteIdentifier(tableName), action)
+		if err := fm.exec(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to %s triggers on table %s: %w", strings.ToLower(action), tableName, err)
+		}
+	}
 	return nil
 }
 
-// LoadFixturesFromDir loads all YAML fixtures from a directory
-func (fm *FixtureManager) LoadFixturesFromDir(fixturesDir string) error {
-	entries, err := os.ReadDir(fixturesDir)
+// cleanupLargeTables truncates the tables above the tracking threshold when configured to
+// With LargeTableCreatedAt the created-at cleanup removes their rows instead
+func (fm *FixtureManager) cleanupLargeTables(ctx context.Context, tx *sql.Tx) error {
+	tables := make([]string, 0, len(fm.largeTables))
+	for tableName := range fm.largeTables {
+		tables = append(tables, tableName)
+	}
+	sort.Strings(tables)
+	tables, err := fm.cleanupOrder(ctx, tx, tables)
 	if err != nil {
-		return fmt.Errorf("failed to read fixtures directory: %w", err)
+		return err
 	}
 
-	for _, entry := range entries {
-		if !entry.IsDir() && fm.isFixtureFile(entry.Name()) {
-			fixturePath := filepath.Join(fixturesDir, entry.Name())
-			if err := fm.LoadYAMLFixtures(fixturePath); err != nil {
-				return fmt.Errorf("failed to load fixture %s: %w", entry.Name(), err)
+	for _, tableName := range tables {
+		if fm.config.LargeTableCleanup == LargeTableCreatedAt {
+			if fm.tableConfigs[tableName].CreatedAtColumn == "" {
+				return fmt.Errorf("table %s exceeded the tracking threshold but has no created-at column configured", tableName)
 			}
+			continue
+		}
+
+		//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+		if err := fm.exec(ctx, tx, fmt.Sprintf("TRUNCATE TABLE %s", fm.config.Dialect.QuoteIdentifier(tableName))); err != nil {
+			return fmt.Errorf("failed to truncate table %s: %w", tableName, err)
 		}
 	}
 
 	return nil
 }
 
+// maxDescribedRecords caps how many rows are listed in cleanup errors
+const maxDescribedRecords = 5
+
+// describeRecords lists the sources of tracked rows for error messages
+func describeRecords(records []trackedRecord) string {
+	described := make([]string, 0, maxDescribedRecords+1)
+	for i, record := range records {
+		if i == maxDescribedRecords {
+			described = append(described, fmt.Sprintf("and %d more", len(records)-maxDescribedRecords))
+			break
+		}
+		described = append(described, record.String())
+	}
+	return "rows from " + strings.Join(described, ", ")
+}
+
+// LoadFixturesFromDir loads all fixture files from a directory, dispatching on their extension
+// Each file is loaded in its own transaction, if a file fails the rows of the files
+// committed before it stay tracked so a deferred CleanupFixtures still removes them
+func (fm *FixtureManager) LoadFixturesFromDir(fixturesDir string) error {
+	return fm.LoadFixturesFromDirContext(context.Background(), fixturesDir)
+}
+
+// LoadFixturesFromDirContext loads all fixture files from a directory like LoadFixturesFromDir,
+// cancelling the load when ctx is done
+func (fm *FixtureManager) LoadFixturesFromDirContext(ctx context.Context, fixturesDir string) error {
+	return fm.LoadFixturesFromDirFSContext(ctx, osDirFS{FS: os.DirFS(fixturesDir), dir: fixturesDir}, ".")
+}
+
+// LoadedTables returns the sorted names of the tables that received fixture rows
+func (fm *FixtureManager) LoadedTables() []string {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	tables := make([]string, 0, len(fm.loadedTables))
+	for tableName := range fm.loadedTables {
+		tables = append(tables, tableName)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// VerifyTablesEmpty checks that every loaded table is empty
+// It returns an error naming the tables that still contain rows
+func (fm *FixtureManager) VerifyTablesEmpty(ctx context.Context) error {
+	var residue []string
+	for _, tableName := range fm.LoadedTables() {
+		count, err := fm.CountRows(ctx, tableName, nil)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			residue = append(residue, fmt.Sprintf("%s (%d rows)", tableName, count))
+		}
+	}
+
+	if len(residue) > 0 {
+		return fmt.Errorf("tables still contain rows after cleanup: %s", strings.Join(residue, ", "))
+	}
+
+	return nil
+}
+
 // isFixtureFile checks if a file is a fixture file based on its extension
+// Extensions with a registered parser are fixture files too
 func (fm *FixtureManager) isFixtureFile(filename string) bool {
 	ext := filepath.Ext(filename)
+	if _, ok := fm.config.Parsers[ext]; ok {
+		return true
+	}
 	for _, validExt := range fm.config.FileExtensions {
 		if ext == validExt {
 			return true
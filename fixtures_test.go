@@ -0,0 +1,265 @@
+package testkit
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQualifiedAndKeywordIdentifiers(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.BatchSize = 10
+	fm, fake := newFakeManager(t, config)
+	fm.ConfigureTable("billing.order", []string{"select"})
+	fake.onQuery = func(string, []any) (*fakeRows, error) {
+		return &fakeRows{columns: []string{"select"}, rows: [][]any{{int64(1)}, {int64(2)}}}, nil
+	}
+
+	fixture := writeFixture(t, "orders.yml", `
+billing.order:
+  - select: 1
+    group: a
+  - select: 2
+    group: b
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+
+	want := []string{
+		`INSERT INTO "billing"."order" ("group", "select") VALUES ($1, $2), ($3, $4) RETURNING "select"`,
+		`DELETE FROM "billing"."order" WHERE ("select" = $1) OR ("select" = $2)`,
+	}
+	got := append(fake.queryTexts("INSERT"), fake.queryTexts("DELETE")...)
+	if !slices.Equal(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+func TestQualifiedAndKeywordIdentifiersPostgres(t *testing.T) {
+	db := newPostgresDB(t, `CREATE TABLE "order" (id serial PRIMARY KEY, "group" text NOT NULL)`)
+	var schema string
+	if err := db.QueryRow("SELECT current_schema()").Scan(&schema); err != nil {
+		t.Fatalf("failed to read the test schema: %v", err)
+	}
+	tableName := schema + ".order"
+	fm := NewFixtureManager(db)
+
+	fixture := writeFixture(t, "orders.yml", tableName+":\n  - group: a\n  - group: b\n")
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	if keys := fm.GetInsertedKeys(tableName); len(keys) != 2 {
+		t.Errorf("GetInsertedKeys() = %v, want the two generated ids", keys)
+	}
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+	if count := countTableRows(t, db, "order"); count != 0 {
+		t.Errorf("orders = %d after cleanup, want 0", count)
+	}
+}
+
+func TestCleanupAfterPartiallyFailedDirectoryLoad(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+	returnIDs := fake.onQuery
+	fake.onQuery = func(query string, args []any) (*fakeRows, error) {
+		if strings.HasPrefix(query, `INSERT INTO "orders"`) {
+			return nil, errors.New("orders table is missing")
+		}
+		return returnIDs(query, args)
+	}
+
+	dir := t.TempDir()
+	writeFixtureIn(t, dir, "1_users.yml", "users:\n  - name: alice\n  - name: bob\n")
+	writeFixtureIn(t, dir, "2_orders.yml", "orders:\n  - total: 10\n")
+	writeFixtureIn(t, dir, "3_items.yml", "items:\n  - name: pen\n")
+
+	err := fm.LoadFixturesFromDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "orders table is missing") {
+		t.Fatalf("LoadFixturesFromDir() error = %v, want the orders failure", err)
+	}
+	if keys := fm.GetInsertedKeys("users"); len(keys) != 2 {
+		t.Fatalf("GetInsertedKeys(users) = %v, want the rows of the first file tracked", keys)
+	}
+	if inserts := fake.queryTexts(`INSERT INTO "items"`); len(inserts) != 0 {
+		t.Errorf("items inserted after a failed file: %q", inserts)
+	}
+
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+	deletes := fake.queries("DELETE")
+	if len(deletes) != 1 || !slices.Equal(deletes[0].Args, []any{int64(1), int64(2)}) {
+		t.Errorf("deletes = %v, want the two users of the first file", deletes)
+	}
+}
+
+func TestStatementTimeoutAppliesToEachTransaction(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.StatementTimeout = 250 * time.Millisecond
+	config.CommitPerTable = true
+	fm, fake := newFakeManager(t, config)
+	fake.returnIDs()
+
+	fixture := writeFixture(t, "seed.yml", "users:\n  - name: alice\ntags:\n  - name: go\n")
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	if err := fm.CleanupFixtures(); err != nil {
+		t.Fatalf("CleanupFixtures() error = %v", err)
+	}
+
+	// Every transaction, one per table and the cleanup, starts by setting the timeout
+	statements := fake.queryTexts("")
+	var begins int
+	for i, statement := range statements {
+		if statement != "BEGIN" {
+			continue
+		}
+		begins++
+		if i+1 >= len(statements) || statements[i+1] != "SET LOCAL statement_timeout = 250" {
+			t.Errorf("transaction %d does not set the timeout first: %q", begins, statements[i:])
+		}
+	}
+	if begins != 3 {
+		t.Errorf("transactions = %d, want 3", begins)
+	}
+}
+
+func TestStatementTimeoutCancelsSlowStatementsPostgres(t *testing.T) {
+	db := newPostgresDB(t, `CREATE TABLE users (id serial PRIMARY KEY, name text NOT NULL)`)
+	config := DefaultFixtureConfig()
+	config.StatementTimeout = 100 * time.Millisecond
+	fm := NewFixtureManagerWithConfig(db, config)
+	fm.ConfigureAfterLoad("users", "SELECT pg_sleep(2)")
+
+	start := time.Now()
+	err := fm.LoadYAMLFixtures(writeFixture(t, "users.yml", "users:\n  - name: alice\n"))
+	if err == nil || !strings.Contains(err.Error(), "statement timeout") {
+		t.Fatalf("LoadYAMLFixtures() error = %v, want a statement timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("load took %s, want it cancelled after the timeout", elapsed)
+	}
+	if count := countTableRows(t, db, "users"); count != 0 {
+		t.Errorf("users = %d after the timeout, want the load rolled back", count)
+	}
+}
+
+// rowsFixture returns a fixture of the table with the given number of rows
+func rowsFixture(tableName string, rows int) string {
+	var fixture strings.Builder
+	fmt.Fprintf(&fixture, "%s:\n", tableName)
+	for row := 0; row < rows; row++ {
+		fmt.Fprintf(&fixture, "  - name: row_%d\n", row)
+	}
+	return fixture.String()
+}
+
+func TestLargeTableCleanup(t *testing.T) {
+	tests := []struct {
+		name        string
+		cleanup     LargeTableCleanup
+		createdAt   string
+		wantQueries []string
+		wantErr     string
+	}{
+		{
+			name:        "truncate",
+			cleanup:     LargeTableTruncate,
+			wantQueries: []string{`TRUNCATE TABLE "events"`},
+		},
+		{
+			name:        "created at",
+			cleanup:     LargeTableCreatedAt,
+			createdAt:   "created_at",
+			wantQueries: []string{`DELETE FROM "events" WHERE "created_at" > $1`},
+		},
+		{
+			name:    "created at without column",
+			cleanup: LargeTableCreatedAt,
+			wantErr: "table events exceeded the tracking threshold but has no created-at column configured",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.LargeTableThreshold = 3
+			config.LargeTableCleanup = tt.cleanup
+			fm, fake := newFakeManager(t, config)
+			if tt.createdAt != "" {
+				fm.ConfigureCreatedAtCleanup("events", tt.createdAt)
+			}
+			fake.returnIDs()
+
+			fixture := writeFixture(t, "events.yml", rowsFixture("events", 5)+rowsFixture("users", 2))
+			if err := fm.LoadYAMLFixtures(fixture); err != nil {
+				t.Fatalf("LoadYAMLFixtures() error = %v", err)
+			}
+			if keys := fm.GetInsertedKeys("events"); keys != nil {
+				t.Errorf("GetInsertedKeys(events) = %v, want the keys released above the threshold", keys)
+			}
+			if keys := fm.GetInsertedKeys("users"); len(keys) != 2 {
+				t.Errorf("GetInsertedKeys(users) = %v, want the 2 rows below the threshold tracked", keys)
+			}
+
+			fake.reset()
+			err := fm.CleanupFixtures()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CleanupFixtures() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CleanupFixtures() error = %v", err)
+			}
+
+			var got []string
+			for _, query := range append(fake.queryTexts("DELETE"), fake.queryTexts("TRUNCATE")...) {
+				if strings.Contains(query, `"events"`) {
+					got = append(got, query)
+				}
+			}
+			if !slices.Equal(got, tt.wantQueries) {
+				t.Errorf("events cleanup = %q, want %q", got, tt.wantQueries)
+			}
+			if deletes := fake.queryTexts(`DELETE FROM "users"`); len(deletes) != 1 {
+				t.Errorf("users cleanup = %q, want the tracked rows deleted by key", deletes)
+			}
+		})
+	}
+}
+
+func BenchmarkCleanupLargeTrackedSet(b *testing.B) {
+	fixturePath := writeFixture(b, "events.yml", rowsFixture("events", 10000))
+
+	for _, threshold := range []int{0, 1000} {
+		b.Run(fmt.Sprintf("threshold=%d", threshold), func(b *testing.B) {
+			config := DefaultFixtureConfig()
+			config.BatchSize = 500
+			config.LargeTableThreshold = threshold
+			fm, fake := newFakeManager(b, config)
+			fake.returnIDs()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := fm.LoadYAMLFixtures(fixturePath); err != nil {
+					b.Fatalf("LoadYAMLFixtures() error = %v", err)
+				}
+				if err := fm.CleanupFixtures(); err != nil {
+					b.Fatalf("CleanupFixtures() error = %v", err)
+				}
+				fake.reset()
+			}
+		})
+	}
+}
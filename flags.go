@@ -0,0 +1,36 @@
+package testkit
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Environment variables used as fallbacks by RunnerConfigFromFlags
+const (
+	EnvDBConnectionString = "TESTKIT_DB"
+	EnvBaseURL            = "TESTKIT_BASE_URL"
+	EnvFixturesDir        = "TESTKIT_FIXTURES"
+	EnvHealthCheckPath    = "TESTKIT_HEALTH_PATH"
+)
+
+// RunnerConfigFromFlags registers the standard testkit flags on fs, parses it and returns the resulting configuration
+// Flags: -db, -base-url, -fixtures and -health-path
+// Precedence is flag, then environment variable (see the Env* constants), then the built-in default
+// If fs has already been parsed (e.g. flag.CommandLine in TestMain) it is not parsed again,
+// in which case the flags must be registered before parsing for command line values to apply
+func RunnerConfigFromFlags(fs *flag.FlagSet) (*RunnerConfig, error) {
+	config := &RunnerConfig{}
+	fs.StringVar(&config.DBConnectionString, "db", os.Getenv(EnvDBConnectionString), "database connection string")
+	fs.StringVar(&config.BaseURL, "base-url", os.Getenv(EnvBaseURL), "base URL of the API under test")
+	fs.StringVar(&config.FixturesDir, "fixtures", os.Getenv(EnvFixturesDir), "path to the fixtures directory")
+	fs.StringVar(&config.HealthCheckPath, "health-path", os.Getenv(EnvHealthCheckPath), "health check endpoint path")
+
+	if !fs.Parsed() {
+		if err := fs.Parse(os.Args[1:]); err != nil {
+			return nil, fmt.Errorf("failed to parse flags: %w", err)
+		}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,70 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// osDirFS is os.DirFS remembering its directory, so errors and cleanup reports show operating system paths
+type osDirFS struct {
+	fs.FS
+	dir string
+}
+
+// osFile returns a file system rooted at the directory of an operating system path and the file's name in it
+// The os based loaders go through it so they share the fs.FS code path
+func osFile(fixturePath string) (fs.FS, string) {
+	dir := filepath.Dir(fixturePath)
+	return osDirFS{FS: os.DirFS(dir), dir: dir}, filepath.Base(fixturePath)
+}
+
+// sourceName describes a fixture file for errors and cleanup reports
+func sourceName(fsys fs.FS, name string) string {
+	if d, ok := fsys.(osDirFS); ok {
+		return filepath.Join(d.dir, filepath.FromSlash(name))
+	}
+	return name
+}
+
+// LoadYAMLFixturesFS loads fixtures from a YAML file of a file system, e.g. an embed.FS
+func (fm *FixtureManager) LoadYAMLFixturesFS(fsys fs.FS, name string) error {
+	return fm.LoadYAMLFixturesFSContext(context.Background(), fsys, name)
+}
+
+// LoadYAMLFixturesFSContext loads fixtures from a YAML file of a file system, cancelling the load when ctx is done
+func (fm *FixtureManager) LoadYAMLFixturesFSContext(ctx context.Context, fsys fs.FS, name string) error {
+	return fm.loadFile(ctx, fsys, name, YAMLParser{})
+}
+
+// LoadFixturesFromDirFS loads all fixture files from a directory of a file system, e.g. an embed.FS
+// It behaves like LoadFixturesFromDir, names are slash separated as required by fs.FS
+func (fm *FixtureManager) LoadFixturesFromDirFS(fsys fs.FS, dir string) error {
+	return fm.LoadFixturesFromDirFSContext(context.Background(), fsys, dir)
+}
+
+// LoadFixturesFromDirFSContext loads all fixture files from a directory of a file system like LoadFixturesFromDirFS,
+// cancelling the load when ctx is done
+func (fm *FixtureManager) LoadFixturesFromDirFSContext(ctx context.Context, fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read fixtures directory: %w", err)
+	}
+
+	shuffleSlice(fm.shuffleSource(), entries)
+
+	for _, entry := range entries {
+		if !entry.IsDir() && fm.isFixtureFile(entry.Name()) && !fm.isOverlayFile(entry.Name()) {
+			name := path.Join(dir, entry.Name())
+			parser := fm.parserFor(path.Ext(name))
+			if err := fm.loadFileWithOverlay(ctx, fsys, name, parser); err != nil {
+				return fmt.Errorf("failed to load fixture %s: %w", entry.Name(), err)
+			}
+		}
+	}
+
+	return nil
+}
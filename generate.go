@@ -0,0 +1,249 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/legrch/logger"
+)
+
+// generateKey is the key of a per-table entry producing rows, e.g. "_generate: {count: 1000, columns: {...}}"
+const generateKey = "_generate"
+
+// Generator produces a column value for each generated row
+// r is the seeded random source of the table and n the 1-based number of the row within its generator
+type Generator func(r *rand.Rand, n int) any
+
+// Sequence generates start, start+1, ...
+func Sequence(start int64) Generator {
+	return func(_ *rand.Rand, n int) any {
+		return start + int64(n) - 1
+	}
+}
+
+// Now generates NOW(), bound according to FixtureConfig.NowBinding
+func Now() Generator {
+	return func(*rand.Rand, int) any {
+		return "NOW()"
+	}
+}
+
+// RandomInt generates integers between min and max, both included
+func RandomInt(minValue, maxValue int64) Generator {
+	return func(r *rand.Rand, _ int) any {
+		return minValue + r.Int64N(maxValue-minValue+1)
+	}
+}
+
+// RandomUUID generates version 4 UUIDs from the seeded source, so they repeat with the seed
+func RandomUUID() Generator {
+	return func(r *rand.Rand, _ int) any {
+		var b [16]byte
+		for i := 0; i < len(b); i += 8 {
+			v := r.Uint64()
+			for j := range 8 {
+				b[i+j] = byte(v >> (8 * j))
+			}
+		}
+		return formatUUID(b)
+	}
+}
+
+// Names used by the faker generators
+var (
+	fakeFirstNames = []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi", "ivan", "judy"}
+	fakeLastNames  = []string{"smith", "jones", "miller", "davis", "garcia", "wilson", "moore", "taylor", "clark", "lee"}
+)
+
+// fakers are the kinds supported by Faker
+var fakers = map[string]Generator{
+	"first_name": func(r *rand.Rand, _ int) any {
+		return capitalize(fakeFirstNames[r.IntN(len(fakeFirstNames))])
+	},
+	"last_name": func(r *rand.Rand, _ int) any {
+		return capitalize(fakeLastNames[r.IntN(len(fakeLastNames))])
+	},
+	"name": func(r *rand.Rand, _ int) any {
+		return capitalize(fakeFirstNames[r.IntN(len(fakeFirstNames))]) + " " +
+			capitalize(fakeLastNames[r.IntN(len(fakeLastNames))])
+	},
+	// The row number keeps emails unique for columns with a unique constraint
+	"email": func(r *rand.Rand, n int) any {
+		return fmt.Sprintf("%s.%s%d@example.com",
+			fakeFirstNames[r.IntN(len(fakeFirstNames))], fakeLastNames[r.IntN(len(fakeLastNames))], n)
+	},
+}
+
+// Faker returns the generator of fake values of the given kind: email, name, first_name or last_name
+func Faker(kind string) (Generator, error) {
+	generator, ok := fakers[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown faker kind %q", kind)
+	}
+	return generator, nil
+}
+
+// capitalize upper-cases the first letter of an ASCII word
+func capitalize(word string) string {
+	return strings.ToUpper(word[:1]) + word[1:]
+}
+
+// sequenceDirective parses "!seq [start]", counting from 1 without a start
+func sequenceDirective(arg string) (any, error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return Sequence(1), nil
+	}
+	start, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid !seq start: %w", err)
+	}
+	return Sequence(start), nil
+}
+
+// randomIntDirective parses "!randint <min> <max>"
+func randomIntDirective(arg string) (any, error) {
+	bounds := strings.Fields(arg)
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("!randint requires a minimum and a maximum, e.g. !randint 1 100")
+	}
+	minValue, err := strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid !randint minimum: %w", err)
+	}
+	maxValue, err := strconv.ParseInt(bounds[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid !randint maximum: %w", err)
+	}
+	if maxValue < minValue {
+		return nil, fmt.Errorf("!randint maximum %d is below the minimum %d", maxValue, minValue)
+	}
+	return RandomInt(minValue, maxValue), nil
+}
+
+// LoadGenerated inserts count rows into the table, with column values produced by generators
+// Values that are not a Generator are copied into every row as is
+func (fm *FixtureManager) LoadGenerated(tableName string, count int, columns map[string]any) error {
+	fixtures := TableFixtures{tableName: {{generateKey: map[string]any{"count": count, "columns": columns}}}}
+	return fm.loadFixtures(context.Background(), "generated rows of "+tableName, fixtures, nil, nil)
+}
+
+// expandGenerators replaces the table's _generate entries with the rows they produce
+// Generator values of ordinary rows are resolved too, with the row's position in the table as number
+func (fm *FixtureManager) expandGenerators(tableName string, records []map[string]any) ([]map[string]any, error) {
+	if !hasGenerators(records) {
+		return records, nil
+	}
+
+	// The lock also serializes the use of the table's random source by concurrent loads
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	r := fm.generatorSource(tableName)
+	rows := make([]map[string]any, 0, len(records))
+	for i, record := range records {
+		spec, ok := record[generateKey]
+		if !ok {
+			rows = append(rows, resolveGenerators(record, r, i+1))
+			continue
+		}
+		if len(record) != 1 {
+			return nil, fmt.Errorf("row %d: %s must be the only key of its entry", i, generateKey)
+		}
+
+		count, columns, err := generateSpec(spec)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		for n := 1; n <= count; n++ {
+			rows = append(rows, resolveGenerators(columns, r, n))
+		}
+	}
+	return rows, nil
+}
+
+// hasGenerators reports whether any record needs expandGenerators
+func hasGenerators(records []map[string]any) bool {
+	for _, record := range records {
+		if _, ok := record[generateKey]; ok {
+			return true
+		}
+		for _, value := range record {
+			if _, ok := value.(Generator); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveGenerators returns a copy of the record with its generators called for row number n
+// Columns are visited in name order so a seed always draws the same values
+func resolveGenerators(record map[string]any, r *rand.Rand, n int) map[string]any {
+	columns := make([]string, 0, len(record))
+	for column := range record {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	row := copyRecord(record)
+	for _, column := range columns {
+		if generator, ok := record[column].(Generator); ok {
+			row[column] = generator(r, n)
+		}
+	}
+	return row
+}
+
+// generateSpec checks a _generate entry and returns its row count and columns
+func generateSpec(spec any) (int, map[string]any, error) {
+	fields, ok := spec.(map[string]any)
+	if !ok {
+		return 0, nil, fmt.Errorf("%s must be a map such as {count: 100, columns: {...}}", generateKey)
+	}
+	for field := range fields {
+		if field != "count" && field != "columns" {
+			return 0, nil, fmt.Errorf("unknown %s field %q", generateKey, field)
+		}
+	}
+
+	count, ok := integerValue(fields["count"])
+	if !ok || count < 0 {
+		return 0, nil, fmt.Errorf("%s requires a non-negative integer count", generateKey)
+	}
+	columns, ok := fields["columns"].(map[string]any)
+	if !ok || len(columns) == 0 {
+		return 0, nil, fmt.Errorf("%s requires a map of columns", generateKey)
+	}
+	return int(count), columns, nil
+}
+
+// generatorSource returns the random source of a table's generators, created on first use
+// Each table has its own stream derived from GeneratorSeed, so reordered or parallel loads draw the same values
+// The caller must hold fm.mu
+func (fm *FixtureManager) generatorSource(tableName string) *rand.Rand {
+	if fm.generatorSeed == 0 {
+		fm.generatorSeed = fm.config.GeneratorSeed
+		if fm.generatorSeed == 0 {
+			fm.generatorSeed = time.Now().UnixNano()
+		}
+		logger.Info("Generating fixture values", "seed", fm.generatorSeed)
+	}
+	if fm.generators == nil {
+		fm.generators = make(map[string]*rand.Rand)
+	}
+	r, ok := fm.generators[tableName]
+	if !ok {
+		hash := fnv.New64a()
+		hash.Write([]byte(tableName))
+		r = rand.New(rand.NewPCG(uint64(fm.generatorSeed), hash.Sum64())) //nolint:gosec // G404: not used for security
+		fm.generators[tableName] = r
+	}
+	return r
+}
@@ -0,0 +1,59 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// LoadFixturesGlob loads the fixture files matching a filepath.Glob pattern in sorted order,
+// e.g. "testdata/fixtures/*users*.yml"
+// Matches are filtered by extension, directories are skipped and overlays are merged like in LoadFixturesFromDir
+// A pattern matching no fixture file is an error unless FixtureConfig.AllowEmptyGlob is set
+func (fm *FixtureManager) LoadFixturesGlob(pattern string) error {
+	return fm.LoadFixturesGlobContext(context.Background(), pattern)
+}
+
+// LoadFixturesGlobContext loads fixture files like LoadFixturesGlob, cancelling the load when ctx is done
+// Files loaded before the cancellation stay loaded and tracked for cleanup
+func (fm *FixtureManager) LoadFixturesGlobContext(ctx context.Context, pattern string) error {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid fixture pattern %s: %w", pattern, err)
+	}
+	sort.Strings(matches)
+
+	var files []string
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return fmt.Errorf("failed to stat fixture %s: %w", match, err)
+		}
+		name := filepath.Base(match)
+		if !info.IsDir() && fm.isFixtureFile(name) && !fm.isOverlayFile(name) {
+			files = append(files, match)
+		}
+	}
+	if len(files) == 0 {
+		if !fm.config.AllowEmptyGlob {
+			return fmt.Errorf("fixture pattern %s matches no fixture files", pattern)
+		}
+		log.Printf("Warning: fixture pattern %s matches no fixture files", pattern)
+		return nil
+	}
+
+	shuffleSlice(fm.shuffleSource(), files)
+
+	for _, file := range files {
+		fsys, name := osFile(file)
+		if err := fm.loadFileWithOverlay(ctx, fsys, name, fm.parserFor(path.Ext(name))); err != nil {
+			return fmt.Errorf("failed to load fixture %s: %w", file, err)
+		}
+	}
+
+	return nil
+}
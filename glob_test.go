@@ -0,0 +1,73 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadFixturesGlob(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.OverlaySuffix = "local"
+	fm, fake := newFakeManager(t, config)
+	fake.returnIDs()
+
+	dir := t.TempDir()
+	writeFixtureIn(t, dir, "b_users.yml", "users:\n  - name: bob\n")
+	writeFixtureIn(t, dir, "a_users.yml", "users:\n  - name: alice\n")
+	writeFixtureIn(t, dir, "a_users.local.yml", "users:\n  - name: carol\n")
+	writeFixtureIn(t, dir, "notes_users.txt", "not a fixture")
+	writeFixtureIn(t, dir, "orders.yml", "orders:\n  - total: 1\n")
+	// A directory named like a fixture file is not loaded
+	if err := os.Mkdir(filepath.Join(dir, "c_users.yml"), 0o700); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if err := fm.LoadFixturesGlob(filepath.Join(dir, "*users*")); err != nil {
+		t.Fatalf("LoadFixturesGlob() error = %v", err)
+	}
+
+	var names []any
+	for _, insert := range fake.queries("INSERT") {
+		names = append(names, insert.Args...)
+	}
+	// Files load in sorted order, the overlay is merged onto its base file instead of loading on its own
+	if want := []any{"alice", "carol", "bob"}; !slices.Equal(names, want) {
+		t.Errorf("inserted names = %v, want %v", names, want)
+	}
+}
+
+func TestLoadFixturesGlobNoMatch(t *testing.T) {
+	fm, _ := newFakeManager(t, nil)
+	pattern := filepath.Join(t.TempDir(), "*.yml")
+
+	err := fm.LoadFixturesGlob(pattern)
+	if err == nil || !strings.Contains(err.Error(), "matches no fixture files") {
+		t.Errorf("LoadFixturesGlob() error = %v, want no matching files", err)
+	}
+
+	fm.config.AllowEmptyGlob = true
+	if err := fm.LoadFixturesGlob(pattern); err != nil {
+		t.Errorf("LoadFixturesGlob() with AllowEmptyGlob error = %v", err)
+	}
+}
+
+func TestLoadFixturesGlobContextCancelled(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	dir := t.TempDir()
+	writeFixtureIn(t, dir, "users.yml", "users:\n  - name: alice\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := fm.LoadFixturesGlobContext(ctx, filepath.Join(dir, "*.yml"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadFixturesGlobContext() error = %v, want context.Canceled", err)
+	}
+	if inserts := fake.queries("INSERT"); len(inserts) != 0 {
+		t.Errorf("inserts = %v after cancellation, want none", inserts)
+	}
+}
@@ -0,0 +1,205 @@
+package testkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// EnvUpdateGolden is the environment variable that, when set to true, makes AssertJSONGolden rewrite golden files
+const EnvUpdateGolden = "TESTKIT_UPDATE"
+
+// updateFlagName is the flag of the test binary that also rewrites golden files, when the test package defines it
+const updateFlagName = "update"
+
+// UpdateGolden makes AssertJSONGolden write the actual body to the golden file instead of comparing against it
+// The package defines no flag, bind it to one of the test package if wanted:
+// flag.BoolVar(&testkit.UpdateGolden, "update", false, "rewrite golden files")
+var UpdateGolden bool
+
+// GoldenOption configures AssertJSONGolden
+type GoldenOption func(*goldenOptions)
+
+// goldenOptions holds the settings of a golden comparison
+type goldenOptions struct {
+	ignorePaths [][]string
+}
+
+// IgnorePaths excludes volatile fields from the golden comparison
+// Paths are dot separated keys, "*" matches any key or array element, e.g. "items.*.created_at"
+func IgnorePaths(paths ...string) GoldenOption {
+	return func(o *goldenOptions) {
+		for _, path := range paths {
+			o.ignorePaths = append(o.ignorePaths, strings.Split(path, "."))
+		}
+	}
+}
+
+// AssertJSONGolden compares a JSON response body with a golden file, ignoring object key order
+// With UpdateGolden, TESTKIT_UPDATE=true or a true -update flag of the test package the actual body is written to the
+// golden file instead
+// The response body is consumed and closed
+func AssertJSONGolden(t testing.TB, resp *http.Response, goldenPath string, opts ...GoldenOption) {
+	t.Helper()
+
+	options := &goldenOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("failed to read response body: %v", err)
+	}
+
+	actual, err := normalizeJSON(body, options.ignorePaths)
+	if err != nil {
+		t.Fatalf("response body is not valid JSON: %v", err)
+	}
+
+	if shouldUpdateGolden() {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		// Keep the raw body so ignored fields are still visible in the golden file
+		pretty, err := normalizeJSON(body, nil)
+		if err != nil {
+			t.Fatalf("failed to format response body: %v", err)
+		}
+		if err := os.WriteFile(goldenPath, pretty, 0o600); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with %s=true to create it): %v", EnvUpdateGolden, err)
+	}
+	expected, err := normalizeJSON(golden, options.ignorePaths)
+	if err != nil {
+		t.Fatalf("golden file %s is not valid JSON: %v", goldenPath, err)
+	}
+
+	if !bytes.Equal(expected, actual) {
+		t.Errorf("response does not match golden file %s (-expected +actual):\n%s",
+			goldenPath, lineDiff(string(expected), string(actual)))
+	}
+}
+
+// shouldUpdateGolden reports whether golden files are rewritten
+// The -update flag is looked up when comparing, as the test package defines its flags after this package is loaded
+func shouldUpdateGolden() bool {
+	if UpdateGolden {
+		return true
+	}
+	if f := flag.Lookup(updateFlagName); f != nil {
+		if update, _ := strconv.ParseBool(f.Value.String()); update {
+			return true
+		}
+	}
+	update, _ := strconv.ParseBool(os.Getenv(EnvUpdateGolden))
+	return update
+}
+
+// normalizeJSON re-encodes a JSON document with sorted keys and indentation, removing ignored paths
+func normalizeJSON(data []byte, ignorePaths [][]string) ([]byte, error) {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	for _, path := range ignorePaths {
+		value = removePath(value, path)
+	}
+
+	normalized, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(normalized, '\n'), nil
+}
+
+// removePath deletes the values at a path from a decoded JSON document
+func removePath(value any, path []string) any {
+	if len(path) == 0 {
+		return value
+	}
+	key, rest := path[0], path[1:]
+
+	switch v := value.(type) {
+	case map[string]any:
+		for k, item := range v {
+			if key != "*" && key != k {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(v, k)
+			} else {
+				v[k] = removePath(item, rest)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			if key != "*" && key != strconv.Itoa(i) {
+				continue
+			}
+			if len(rest) == 0 {
+				v[i] = nil
+			} else {
+				v[i] = removePath(item, rest)
+			}
+		}
+	}
+	return value
+}
+
+// lineDiff returns a line based diff of two texts, prefixing removed lines with "-" and added lines with "+"
+func lineDiff(expected, actual string) string {
+	a := strings.Split(strings.TrimSuffix(expected, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	// Longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&out, "  %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&out, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return out.String()
+}
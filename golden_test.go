@@ -0,0 +1,139 @@
+package testkit
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// recordingTB records the failures reported through Errorf and Fatalf, other methods are those of the wrapped test
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+// Errorf implements testing.TB without failing the wrapped test
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// Fatalf implements testing.TB without failing the wrapped test, stopping the goroutine like the wrapped test would
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+// run calls fn with the recorder on a goroutine of its own, so that Fatalf only ends fn
+func (r *recordingTB) run(fn func(t testing.TB)) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(r)
+	}()
+	<-done
+}
+
+// jsonResponse returns a response with a JSON body
+func jsonResponse(body string) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+func TestAssertJSONGolden(t *testing.T) {
+	goldenPath := filepath.Join(t.TempDir(), "golden", "user.json")
+	body := `{"name": "alice", "id": 1, "created_at": "2024-01-01"}`
+
+	t.Setenv(EnvUpdateGolden, "true")
+	AssertJSONGolden(t, jsonResponse(body), goldenPath)
+	written, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("golden file not written: %v", err)
+	}
+	if !strings.Contains(string(written), `"created_at"`) {
+		t.Errorf("golden file %s lost the raw body fields", written)
+	}
+
+	t.Setenv(EnvUpdateGolden, "")
+	// Key order does not count and ignored paths are left out of the comparison
+	AssertJSONGolden(t, jsonResponse(`{"id": 1, "created_at": "2025-06-30", "name": "alice"}`), goldenPath,
+		IgnorePaths("created_at"))
+
+	recorder := &recordingTB{TB: t}
+	AssertJSONGolden(recorder, jsonResponse(`{"id": 2, "name": "alice", "created_at": "2024-01-01"}`), goldenPath)
+	if len(recorder.errors) != 1 || !strings.Contains(recorder.errors[0], "-   \"id\": 1,\n+   \"id\": 2,") {
+		t.Errorf("mismatch errors = %q, want a diff of the id line", recorder.errors)
+	}
+}
+
+func TestShouldUpdateGolden(t *testing.T) {
+	t.Setenv(EnvUpdateGolden, "")
+	if shouldUpdateGolden() {
+		t.Fatal("shouldUpdateGolden() = true without UpdateGolden or TESTKIT_UPDATE")
+	}
+
+	t.Setenv(EnvUpdateGolden, "1")
+	if !shouldUpdateGolden() {
+		t.Error("shouldUpdateGolden() = false with TESTKIT_UPDATE=1")
+	}
+
+	t.Setenv(EnvUpdateGolden, "")
+	UpdateGolden = true
+	t.Cleanup(func() { UpdateGolden = false })
+	if !shouldUpdateGolden() {
+		t.Error("shouldUpdateGolden() = false with UpdateGolden")
+	}
+}
+
+func TestNormalizeJSONIgnorePaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{name: "no paths", want: `{"items":[{"at":1,"id":1},{"at":2,"id":2}],"total":2}`},
+		{name: "top level key", paths: []string{"total"}, want: `{"items":[{"at":1,"id":1},{"at":2,"id":2}]}`},
+		{name: "wildcard element", paths: []string{"items.*.at"}, want: `{"items":[{"id":1},{"id":2}],"total":2}`},
+		{name: "array index", paths: []string{"items.1"}, want: `{"items":[{"at":1,"id":1},null],"total":2}`},
+		{name: "missing path", paths: []string{"meta.page"}, want: `{"items":[{"at":1,"id":1},{"at":2,"id":2}],"total":2}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths [][]string
+			for _, path := range tt.paths {
+				paths = append(paths, strings.Split(path, "."))
+			}
+			got, err := normalizeJSON([]byte(`{"total": 2, "items": [{"id": 1, "at": 1}, {"id": 2, "at": 2}]}`), paths)
+			if err != nil {
+				t.Fatalf("normalizeJSON() error = %v", err)
+			}
+			compact := strings.Join(strings.Fields(string(got)), "")
+			if compact != tt.want {
+				t.Errorf("normalizeJSON() = %s, want %s", compact, tt.want)
+			}
+		})
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name             string
+		expected, actual string
+		want             string
+	}{
+		{name: "equal", expected: "a\nb\n", actual: "a\nb\n", want: "  a\n  b\n"},
+		{name: "changed line", expected: "a\nb\nc\n", actual: "a\nx\nc\n", want: "  a\n- b\n+ x\n  c\n"},
+		{name: "added line", expected: "a\nc", actual: "a\nb\nc", want: "  a\n+ b\n  c\n"},
+		{name: "removed line", expected: "a\nb\nc", actual: "a\nc", want: "  a\n- b\n  c\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lineDiff(tt.expected, tt.actual); got != tt.want {
+				t.Errorf("lineDiff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+// NotServingError is returned by a gRPC health probe when the server answers with a status other than SERVING
+type NotServingError struct {
+	Service string
+	Status  healthpb.HealthCheckResponse_ServingStatus
+}
+
+// Error implements error
+func (e *NotServingError) Error() string {
+	return fmt.Sprintf("gRPC service %q is %s", e.Service, e.Status)
+}
+
+// newGRPCConn creates the client connection to GRPCAddress
+// Without GRPCDialOptions it uses TLSConfig when set and plaintext otherwise
+func newGRPCConn(config *RunnerConfig) (*grpc.ClientConn, error) {
+	options := config.GRPCDialOptions
+	if len(options) == 0 {
+		creds := insecure.NewCredentials()
+		if config.TLSConfig != nil {
+			creds = credentials.NewTLS(config.TLSConfig)
+		}
+		options = []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	}
+
+	conn, err := grpc.NewClient(config.GRPCAddress, options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client for %s: %w", config.GRPCAddress, err)
+	}
+	return conn, nil
+}
+
+// probeGRPC sends a single grpc.health.v1.Health/Check request for service
+func probeGRPC(ctx context.Context, conn grpc.ClientConnInterface, service string) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return err
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return &NotServingError{Service: service, Status: resp.GetStatus()}
+	}
+	return nil
+}
+
+// WaitForGRPC polls the standard gRPC health service until service reports SERVING
+// An empty service checks the overall health of the server
+// It follows the attempts, timeout and backoff of the policy like WaitForHTTP, HTTP specific fields are ignored
+func WaitForGRPC(ctx context.Context, conn grpc.ClientConnInterface, service string, policy RetryPolicy) error {
+	return poll(ctx, policy.withDefaults(), []any{"grpc_service", service}, func(ctx context.Context) error {
+		return probeGRPC(ctx, conn, service)
+	})
+}
+
+// GetGRPCConn returns the client connection to GRPCAddress, nil when no gRPC address is configured
+// The connection is shared by all tests and closed by Cleanup
+func (r *TestRunner) GetGRPCConn() *grpc.ClientConn {
+	return r.grpcConn
+}
+
+// GRPCSmoke checks that a gRPC server really serves requests, not just accepts connections
+// It lists the services through server reflection, which the server must register, and when the standard
+// health service is among them also checks the overall health, returning the sorted service names
+func GRPCSmoke(ctx context.Context, conn grpc.ClientConnInterface) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reflection stream: %w", err)
+	}
+	if err := stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to request service list: %w", err)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services: %w", err)
+	}
+	_ = stream.CloseSend()
+
+	if errResp := resp.GetErrorResponse(); errResp != nil {
+		return nil, fmt.Errorf("reflection failed with code %d: %s", errResp.GetErrorCode(), errResp.GetErrorMessage())
+	}
+	var services []string
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		services = append(services, service.GetName())
+	}
+	slices.Sort(services)
+
+	if slices.Contains(services, healthpb.Health_ServiceDesc.ServiceName) {
+		if err := probeGRPC(ctx, conn, ""); err != nil {
+			return services, fmt.Errorf("health check failed: %w", err)
+		}
+	}
+	return services, nil
+}
@@ -0,0 +1,79 @@
+package testkit
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/legrch/logger"
+)
+
+// redactedValue replaces the value of sensitive headers in logs
+const redactedValue = "[REDACTED]"
+
+// DefaultRedactedHeaders are the headers whose values are never logged by LoggingRoundTripper
+var DefaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// LoggingRoundTripper logs every request and response passing through it
+// Values of the configured sensitive headers are redacted
+type LoggingRoundTripper struct {
+	// Next is the underlying transport (defaults to http.DefaultTransport)
+	Next http.RoundTripper
+	// RedactHeaders lists headers whose values are replaced in logs (defaults to DefaultRedactedHeaders)
+	RedactHeaders []string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *LoggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	start := time.Now()
+	resp, err := next.RoundTrip(req)
+	duration := time.Since(start)
+
+	if err != nil {
+		logger.Info("HTTP request failed",
+			"method", req.Method,
+			"url", req.URL.String(),
+			"request_headers", t.redact(req.Header),
+			"duration", duration,
+			"error", err,
+		)
+		return nil, err
+	}
+
+	logger.Info("HTTP request",
+		"method", req.Method,
+		"url", req.URL.String(),
+		"status", resp.StatusCode,
+		"request_headers", t.redact(req.Header),
+		"response_headers", t.redact(resp.Header),
+		"duration", duration,
+	)
+
+	return resp, nil
+}
+
+// redact returns a copy of the headers with sensitive values replaced
+func (t *LoggingRoundTripper) redact(header http.Header) map[string]string {
+	sensitive := t.RedactHeaders
+	if sensitive == nil {
+		sensitive = DefaultRedactedHeaders
+	}
+
+	result := make(map[string]string, len(header))
+	for name, values := range header {
+		result[name] = strings.Join(values, ", ")
+		for _, s := range sensitive {
+			if strings.EqualFold(name, s) {
+				result[name] = redactedValue
+				break
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,64 @@
+package testkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// GetJSON sends a GET request to the path and decodes a 2xx JSON response into out, unless out is nil
+// See PostJSON for how failures and the returned response are handled
+func (r *TestRunner) GetJSON(t testing.TB, path string, out any) *http.Response {
+	t.Helper()
+	return r.doJSON(t, http.MethodGet, path, nil, out)
+}
+
+// PostJSON sends body to the path as JSON and decodes a 2xx JSON response into out, unless out is nil
+// Transport errors and undecodable 2xx responses fail the test, other status codes are left to the caller
+// The returned response body has been read and can be read again, e.g. by AssertJSONGolden
+func (r *TestRunner) PostJSON(t testing.TB, path string, body, out any) *http.Response {
+	t.Helper()
+	return r.doJSON(t, http.MethodPost, path, body, out)
+}
+
+// doJSON sends a JSON request with the runner's HTTP client and decodes the response
+func (r *TestRunner) doJSON(t testing.TB, method, path string, body, out any) *http.Response {
+	t.Helper()
+
+	if r.httpClient == nil {
+		t.Fatalf("%s %s: the runner has no HTTP client in data-only mode", method, path)
+	}
+	reader, _, err := requestBody(body)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+
+	req, err := http.NewRequestWithContext(t.Context(), method, r.URL(path), reader)
+	if err != nil {
+		t.Fatalf("%s %s: failed to create request: %v", method, path, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: request failed: %v", method, path, err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("%s %s: failed to read response body: %v", method, path, err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+
+	if out != nil && resp.StatusCode >= 200 && resp.StatusCode < 300 && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: failed to decode response with status %d: %v, body: %s", method, path, resp.StatusCode, err, data)
+		}
+	}
+	return resp
+}
@@ -0,0 +1,141 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// jsonServer echoes the method, headers and JSON body of requests to /echo and answers other paths per status
+func jsonServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/echo":
+			var body any
+			if req.ContentLength > 0 {
+				if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{
+				"method":       req.Method,
+				"accept":       req.Header.Get("Accept"),
+				"content_type": req.Header.Get("Content-Type"),
+				"body":         body,
+			})
+		case "/invalid":
+			fmt.Fprint(w, "not json")
+		default:
+			http.Error(w, `{"error": "not found"}`, http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// echoed is the answer of jsonServer's /echo endpoint
+type echoed struct {
+	Method      string `json:"method"`
+	Accept      string `json:"accept"`
+	ContentType string `json:"content_type"`
+	Body        any    `json:"body"`
+}
+
+func TestGetJSON(t *testing.T) {
+	runner, _ := newFakeRunner(t, &RunnerConfig{BaseURL: jsonServer(t).URL})
+
+	var got echoed
+	resp := runner.GetJSON(t, "/echo", &got)
+
+	want := echoed{Method: http.MethodGet, Accept: "application/json"}
+	if got != want {
+		t.Errorf("GetJSON() decoded %+v, want %+v", got, want)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("GetJSON() status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestPostJSON(t *testing.T) {
+	runner, _ := newFakeRunner(t, &RunnerConfig{BaseURL: jsonServer(t).URL})
+
+	var got echoed
+	resp := runner.PostJSON(t, "/echo", map[string]any{"name": "alice"}, &got)
+
+	if got.Method != http.MethodPost || got.ContentType != "application/json" || got.Accept != "application/json" {
+		t.Errorf("PostJSON() sent %+v, want a JSON POST", got)
+	}
+	if body, ok := got.Body.(map[string]any); !ok || body["name"] != "alice" {
+		t.Errorf("PostJSON() sent body %v, want the encoded map", got.Body)
+	}
+	// The body was read for decoding and can be read again
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || !strings.Contains(string(data), `"method":"POST"`) {
+		t.Errorf("response body = %q, %v, want the echoed request", data, err)
+	}
+}
+
+func TestJSONClientLeavesErrorStatusesToCaller(t *testing.T) {
+	runner, _ := newFakeRunner(t, &RunnerConfig{BaseURL: jsonServer(t).URL})
+
+	var got map[string]any
+	resp := runner.GetJSON(t, "/missing", &got)
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GetJSON() status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if got != nil {
+		t.Errorf("GetJSON() decoded %v from an error response, want nothing", got)
+	}
+}
+
+func TestJSONClientFailures(t *testing.T) {
+	server := jsonServer(t)
+	tests := []struct {
+		name     string
+		dataOnly bool
+		call     func(t testing.TB, runner *TestRunner)
+		want     string
+	}{
+		{
+			name: "undecodable response",
+			call: func(t testing.TB, runner *TestRunner) { runner.GetJSON(t, "/invalid", &map[string]any{}) },
+			want: "GET /invalid: failed to decode response with status 200",
+		},
+		{
+			name: "unencodable body",
+			call: func(t testing.TB, runner *TestRunner) { runner.PostJSON(t, "/echo", func() {}, nil) },
+			want: "POST /echo: failed to encode request body",
+		},
+		{
+			name:     "data-only runner",
+			dataOnly: true,
+			call:     func(t testing.TB, runner *TestRunner) { runner.GetJSON(t, "/echo", nil) },
+			want:     "GET /echo: the runner has no HTTP client in data-only mode",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &RunnerConfig{BaseURL: server.URL}
+			if tt.dataOnly {
+				config = &RunnerConfig{}
+			}
+			runner, _ := newFakeRunner(t, config)
+
+			recorder := &recordingTB{TB: t}
+			recorder.run(func(t testing.TB) { tt.call(t, runner) })
+
+			if len(recorder.errors) != 1 || !strings.HasPrefix(recorder.errors[0], tt.want) {
+				t.Errorf("failures = %q, want one starting with %q", recorder.errors, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package testkit
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// connectionStats snapshots the pool statistics of each database
+func connectionStats(dbs map[string]*sql.DB) map[string]sql.DBStats {
+	stats := make(map[string]sql.DBStats, len(dbs))
+	for name, db := range dbs {
+		stats[name] = db.Stats()
+	}
+	return stats
+}
+
+// checkConnectionLeaks reports databases with more connections in use than at startup
+// Idle connections are kept by the pool and are not leaks, only connections still held by the caller count
+func checkConnectionLeaks(before, after map[string]sql.DBStats) error {
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var leaks []string
+	for _, name := range names {
+		start, end := before[name], after[name]
+		log.Printf("%s database connections: in use %d -> %d, open %d -> %d",
+			name, start.InUse, end.InUse, start.OpenConnections, end.OpenConnections)
+		if end.InUse > start.InUse {
+			leaks = append(leaks, fmt.Sprintf("%s (%d in use, %d at startup)", name, end.InUse, start.InUse))
+		}
+	}
+
+	if len(leaks) > 0 {
+		return fmt.Errorf("database connections leaked: %s", strings.Join(leaks, ", "))
+	}
+	return nil
+}
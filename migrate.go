@@ -0,0 +1,79 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// migrate applies MigrationsDir, then MigrateFunc, to the primary database
+func (r *TestRunner) migrate() error {
+	if r.config.MigrationsDir != "" {
+		if err := ApplyMigrations(context.Background(), r.db, r.config.MigrationsDir); err != nil {
+			return err
+		}
+	}
+	if r.config.MigrateFunc != nil {
+		if err := r.config.MigrateFunc(r.db); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+	return nil
+}
+
+// ApplyMigrations executes the .sql files of dir in name order, each in its own transaction
+// Files ending in .down.sql are rollbacks and skipped, so the up/down naming of common migration tools works
+// There is no bookkeeping of applied files, the database is expected to start empty
+// A file may hold several statements if the driver accepts them in one Exec, as lib/pq does
+func ApplyMigrations(ctx context.Context, db *sql.DB, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".sql" || strings.HasSuffix(name, ".down.sql") {
+			continue
+		}
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	for _, name := range files {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		if err := execMigration(ctx, db, string(content)); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", name, err)
+		}
+		log.Printf("Applied migration %s", name)
+	}
+	return nil
+}
+
+// execMigration runs the statements of one migration file in a transaction
+func execMigration(ctx context.Context, db *sql.DB, statements string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Printf("failed to rollback migration transaction: %v", err)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,44 @@
+package testkit
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CamelToSnake converts a camelCase or PascalCase name to snake_case
+// Acronyms are kept together, e.g. "userID" becomes "user_id" and "HTTPStatus" becomes "http_status"
+func CamelToSnake(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	b.Grow(len(name) + 4)
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+					b.WriteRune('_')
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// mapColumnNames returns a copy of the record with every column renamed by the mapper
+func mapColumnNames(record map[string]any, mapper func(string) string) map[string]any {
+	if mapper == nil {
+		return record
+	}
+
+	mapped := make(map[string]any, len(record))
+	for column, value := range record {
+		mapped[mapper(column)] = value
+	}
+	return mapped
+}
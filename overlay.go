@@ -0,0 +1,136 @@
+package testkit
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// LoadYAMLFixturesWithOverlay loads a base fixture file merged with an overlay file
+// Overlay rows replace base rows of the same table with the same primary key, other overlay rows are appended
+func (fm *FixtureManager) LoadYAMLFixturesWithOverlay(basePath, overlayPath string) error {
+	baseFS, baseName := osFile(basePath)
+	base, baseOrder, err := fm.readFixtures(baseFS, baseName, YAMLParser{})
+	if err != nil {
+		return err
+	}
+	overlayFS, overlayName := osFile(overlayPath)
+	overlay, overlayOrder, err := fm.readFixtures(overlayFS, overlayName, YAMLParser{})
+	if err != nil {
+		return err
+	}
+
+	order := mergeOrder(baseOrder, overlayOrder)
+	return fm.loadFixtures(context.Background(), basePath, fm.mergeOverlay(base, overlay), order, nil)
+}
+
+// overlayPath returns the overlay file of a fixture file according to OverlaySuffix,
+// e.g. users.local.yml for users.yml, or an empty string when there is none
+func (fm *FixtureManager) overlayPath(fsys fs.FS, name string) string {
+	if fm.config.OverlaySuffix == "" {
+		return ""
+	}
+
+	ext := path.Ext(name)
+	candidate := strings.TrimSuffix(name, ext) + "." + fm.config.OverlaySuffix + ext
+	if _, err := fs.Stat(fsys, candidate); err != nil {
+		return ""
+	}
+	return candidate
+}
+
+// isOverlayFile reports whether a file name follows the overlay naming convention
+func (fm *FixtureManager) isOverlayFile(filename string) bool {
+	if fm.config.OverlaySuffix == "" {
+		return false
+	}
+	return strings.HasSuffix(strings.TrimSuffix(filename, filepath.Ext(filename)), "."+fm.config.OverlaySuffix)
+}
+
+// loadFileWithOverlay loads a fixture file, merging its overlay file when one exists
+func (fm *FixtureManager) loadFileWithOverlay(
+	ctx context.Context, fsys fs.FS, name string, parser FixtureParser,
+) error {
+	overlayName := fm.overlayPath(fsys, name)
+	if overlayName == "" {
+		return fm.loadFile(ctx, fsys, name, parser)
+	}
+	return fm.loadMerged(ctx, fsys, name, overlayName, parser)
+}
+
+// loadMerged parses a base and an overlay file with the same parser and loads the merged result
+func (fm *FixtureManager) loadMerged(
+	ctx context.Context, fsys fs.FS, baseName, overlayName string, parser FixtureParser,
+) error {
+	base, baseOrder, err := fm.readFixtures(fsys, baseName, parser)
+	if err != nil {
+		return err
+	}
+	overlay, overlayOrder, err := fm.readFixtures(fsys, overlayName, parser)
+	if err != nil {
+		return err
+	}
+
+	order := mergeOrder(baseOrder, overlayOrder)
+	return fm.loadFixtures(ctx, sourceName(fsys, baseName), fm.mergeOverlay(base, overlay), order, nil)
+}
+
+// mergeOrder returns the table order of a merged base and overlay file, the base order followed by the tables only
+// listed by the overlay. The order of a file that lists nothing is nil
+func mergeOrder(base, overlay []string) []string {
+	order := slices.Clone(base)
+	for _, tableName := range overlay {
+		if !slices.Contains(order, tableName) {
+			order = append(order, tableName)
+		}
+	}
+	return order
+}
+
+// mergeOverlay merges overlay fixtures onto base fixtures by table and primary key
+// A row without every primary key column never matches and is appended
+func (fm *FixtureManager) mergeOverlay(base, overlay TableFixtures) TableFixtures {
+	merged := make(TableFixtures, len(base))
+	for tableName, records := range base {
+		merged[tableName] = append([]map[string]any(nil), records...)
+	}
+
+	for tableName, overlayRecords := range overlay {
+		primaryKeys := fm.getPrimaryKeys(tableName)
+		for _, overlayRecord := range overlayRecords {
+			replaced := false
+			for i, baseRecord := range merged[tableName] {
+				if fm.samePrimaryKey(baseRecord, overlayRecord, primaryKeys) {
+					merged[tableName][i] = overlayRecord
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				merged[tableName] = append(merged[tableName], overlayRecord)
+			}
+		}
+	}
+
+	return merged
+}
+
+// samePrimaryKey reports whether two fixture rows have the same values for every primary key column
+// Column names are compared after ColumnNameMapper, like at insert time
+func (fm *FixtureManager) samePrimaryKey(a, b map[string]any, primaryKeys []string) bool {
+	a = mapColumnNames(a, fm.config.ColumnNameMapper)
+	b = mapColumnNames(b, fm.config.ColumnNameMapper)
+
+	for _, pk := range primaryKeys {
+		av, aok := a[pk]
+		bv, bok := b[pk]
+		if !aok || !bok || !reflect.DeepEqual(av, bv) {
+			return false
+		}
+	}
+	return len(primaryKeys) > 0
+}
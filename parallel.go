@@ -0,0 +1,59 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// loadTablesParallel inserts the tables of the fixtures concurrently, each in its own transaction
+// At most ParallelWorkers tables load at once and a table starts only after its dependencies committed
+func (fm *FixtureManager) loadTablesParallel(
+	ctx context.Context, source string, fixtures TableFixtures, tableNames []string, load *loadState,
+) error {
+	// tableOrder already rejected dependency cycles, so every table eventually becomes ready
+	dependencies := fm.tableDependencies(tableNames, fm.referencedSnapshot(), load.dependencies)
+
+	type result struct {
+		done chan struct{}
+		err  error
+	}
+	results := make(map[string]*result, len(tableNames))
+	for _, tableName := range tableNames {
+		results[tableName] = &result{done: make(chan struct{})}
+	}
+
+	workers := make(chan struct{}, fm.config.ParallelWorkers)
+	var wg sync.WaitGroup
+	for _, tableName := range tableNames {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := results[tableName]
+			defer close(r.done)
+
+			for _, dependency := range dependencies[tableName] {
+				parent := results[dependency]
+				<-parent.done
+				if parent.err != nil {
+					r.err = fmt.Errorf("skipped table %s because table %s failed to load", tableName, dependency)
+					return
+				}
+			}
+
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			r.err = fm.loadTables(ctx, source, fixtures, []string{tableName}, load)
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, tableName := range tableNames {
+		if err := results[tableName].err; err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
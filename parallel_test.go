@@ -0,0 +1,122 @@
+package testkit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// parallelFixture has two dependency chains, users <- posts <- comments and tags, plus a table of its own
+const parallelFixture = `
+users:
+  - _alias: alice
+    name: alice
+tags:
+  - name: go
+posts:
+  - _alias: hello
+    user_id: $users.alice.id
+    title: hello
+comments:
+  - post_id: $posts.hello.id
+    body: first
+audit_log:
+  - message: loaded
+`
+
+// newParallelManager returns a manager loading with the given workers on a fake database that hands out ids
+func newParallelManager(t testing.TB, workers int) (*FixtureManager, *fakeDB) {
+	t.Helper()
+
+	config := DefaultFixtureConfig()
+	config.ParallelWorkers = workers
+	fm, fake := newFakeManager(t, config)
+	fm.ConfigureTableDependencies("posts", []string{"users", "tags"})
+	fm.ConfigureTableDependencies("comments", []string{"posts"})
+	fake.returnIDs()
+	return fm, fake
+}
+
+func TestLoadTablesParallelRespectsDependencies(t *testing.T) {
+	fm, fake := newParallelManager(t, 4)
+	fixture := writeFixture(t, "parallel.yml", parallelFixture)
+
+	for run := 0; run < 20; run++ {
+		fake.reset()
+		if err := fm.LoadYAMLFixtures(fixture); err != nil {
+			t.Fatalf("LoadYAMLFixtures() error = %v", err)
+		}
+
+		position := make(map[string]int)
+		for i, insert := range fake.queryTexts("INSERT") {
+			tableName := strings.Trim(strings.Fields(insert)[2], `"`)
+			position[tableName] = i
+		}
+		if len(position) != 5 {
+			t.Fatalf("inserted tables = %v, want 5 tables", position)
+		}
+		for child, parents := range map[string][]string{"posts": {"users", "tags"}, "comments": {"posts"}} {
+			for _, parent := range parents {
+				if position[child] < position[parent] {
+					t.Errorf("run %d: %s inserted before %s", run, child, parent)
+				}
+			}
+		}
+		if err := fm.CleanupFixtures(); err != nil {
+			t.Fatalf("CleanupFixtures() error = %v", err)
+		}
+	}
+}
+
+func TestLoadTablesParallelSkipsDependentsOfFailedTables(t *testing.T) {
+	fm, fake := newParallelManager(t, 4)
+	onQuery := fake.onQuery
+	fake.onQuery = func(query string, args []any) (*fakeRows, error) {
+		if strings.HasPrefix(query, `INSERT INTO "users"`) {
+			return nil, errors.New("users are read-only")
+		}
+		return onQuery(query, args)
+	}
+
+	err := fm.LoadYAMLFixtures(writeFixture(t, "parallel.yml", parallelFixture))
+	if err == nil {
+		t.Fatal("LoadYAMLFixtures() error = nil, want the users failure")
+	}
+	for _, want := range []string{"users are read-only", "skipped table posts", "skipped table comments"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadYAMLFixtures() error = %v, want it to contain %q", err, want)
+		}
+	}
+	for _, insert := range fake.queryTexts("INSERT") {
+		if strings.Contains(insert, `"posts"`) || strings.Contains(insert, `"comments"`) {
+			t.Errorf("dependent table inserted after its dependency failed: %s", insert)
+		}
+	}
+}
+
+func BenchmarkLoadTablesParallel(b *testing.B) {
+	var fixture strings.Builder
+	for table := 0; table < 20; table++ {
+		fmt.Fprintf(&fixture, "table_%d:\n", table)
+		for row := 0; row < 50; row++ {
+			fmt.Fprintf(&fixture, "  - name: row_%d\n", row)
+		}
+	}
+	fixturePath := writeFixture(b, "bench.yml", fixture.String())
+
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			fm, _ := newParallelManager(b, workers)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := fm.LoadYAMLFixtures(fixturePath); err != nil {
+					b.Fatalf("LoadYAMLFixtures() error = %v", err)
+				}
+				if err := fm.CleanupFixtures(); err != nil {
+					b.Fatalf("CleanupFixtures() error = %v", err)
+				}
+			}
+		})
+	}
+}
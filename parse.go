@@ -0,0 +1,359 @@
+package testkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"gopkg.in/yaml.v3"
+)
+
+// FixtureParser parses the contents of a fixture file into table fixtures
+type FixtureParser interface {
+	Parse(content []byte) (TableFixtures, error)
+}
+
+// FixtureParserFunc adapts a function to the FixtureParser interface
+type FixtureParserFunc func(content []byte) (TableFixtures, error)
+
+// Parse calls f(content)
+func (f FixtureParserFunc) Parse(content []byte) (TableFixtures, error) {
+	return f(content)
+}
+
+// orderedParser is implemented by parsers of formats that can fix the order of tables
+// The order is nil when the document does not specify one
+type orderedParser interface {
+	parseOrdered(content []byte) (TableFixtures, []string, error)
+}
+
+// YAMLParser parses YAML fixtures, resolving value directives such as !nextval
+// The document is either a map of table name to rows or an ordered list of {table, rows} entries
+type YAMLParser struct{}
+
+// Parse implements FixtureParser
+func (YAMLParser) Parse(content []byte) (TableFixtures, error) {
+	fixtures, _, err := parseYAMLDocument(content)
+	return fixtures, err
+}
+
+// parseOrdered implements orderedParser
+func (YAMLParser) parseOrdered(content []byte) (TableFixtures, []string, error) {
+	return parseYAMLDocument(content)
+}
+
+// JSONParser parses JSON fixtures with the same shape as YAML fixtures
+// Integral numbers decode as int64 and other numbers as float64, matching YAML decoding
+type JSONParser struct{}
+
+// Parse implements FixtureParser
+func (JSONParser) Parse(content []byte) (TableFixtures, error) {
+	fixtures, _, err := parseJSONDocument(content)
+	return fixtures, err
+}
+
+// parseOrdered implements orderedParser
+func (JSONParser) parseOrdered(content []byte) (TableFixtures, []string, error) {
+	return parseJSONDocument(content)
+}
+
+// sqlExpression is a fixture value inserted as a raw SQL expression instead of a bound parameter
+type sqlExpression string
+
+// secretValue is an encrypted fixture value, decrypted by the manager's decryptor before binding
+type secretValue string
+
+// valueDirectives resolve YAML tags such as "!nextval my_seq" into fixture values
+var valueDirectives = map[string]func(arg string) (any, error){
+	// Postgres only: resolves to nextval('<sequence>') evaluated by the server
+	"!nextval": func(arg string) (any, error) {
+		return sequenceExpression("nextval", arg)
+	},
+	// Postgres only: resolves to currval('<sequence>') evaluated by the server
+	"!currval": func(arg string) (any, error) {
+		return sequenceExpression("currval", arg)
+	},
+	// Emits the DEFAULT keyword so the column explicitly takes its database default
+	"!default": func(string) (any, error) {
+		return sqlExpression("DEFAULT"), nil
+	},
+	// Keeps the ciphertext until load time, see FixtureManager.RegisterDecryptor
+	"!secret": func(arg string) (any, error) {
+		return secretValue(strings.TrimSpace(arg)), nil
+	},
+	// Resolves to the manager's RunID at load time
+	"!runid": func(string) (any, error) {
+		return runIDValue{}, nil
+	},
+	// Parses a timestamp so it is bound as time.Time whether or not it was quoted
+	"!timestamp": func(arg string) (any, error) {
+		return parseTimestamp(arg)
+	},
+	// Parses a Go duration such as "24h" into a PostgreSQL interval value
+	"!duration": func(arg string) (any, error) {
+		d, err := time.ParseDuration(strings.TrimSpace(arg))
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration: %w", err)
+		}
+		return intervalLiteral(d), nil
+	},
+	// Generators produce a value per row, see Generator and the _generate entry
+	"!seq":     sequenceDirective,
+	"!randint": randomIntDirective,
+	"!faker": func(arg string) (any, error) {
+		return Faker(strings.TrimSpace(arg))
+	},
+	"!now": func(string) (any, error) {
+		return Now(), nil
+	},
+	"!uuid": func(string) (any, error) {
+		return RandomUUID(), nil
+	},
+}
+
+// sequenceExpression builds a call to a Postgres sequence function
+func sequenceExpression(function, sequence string) (any, error) {
+	sequence = strings.TrimSpace(sequence)
+	if sequence == "" {
+		return nil, fmt.Errorf("!%s requires a sequence name", function)
+	}
+	return sqlExpression(fmt.Sprintf("%s(%s)", function, pq.QuoteLiteral(sequence))), nil
+}
+
+// parseYAMLDocument parses YAML content into table fixtures and the table order of the ordered list format
+// Value directives are resolved while decoding
+func parseYAMLDocument(content []byte) (TableFixtures, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal YAML fixtures: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return TableFixtures{}, nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode && root.Kind != yaml.SequenceNode {
+		return nil, nil, fmt.Errorf("fixture file must be a map of table name to rows or a list of tables, got a %s",
+			nodeKindName(root))
+	}
+
+	value, err := decodeNode(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if entries, ok := value.([]any); ok {
+		return orderedTableFixtures(entries)
+	}
+	tables, _ := value.(map[string]any)
+
+	fixtures, err := tableFixtures(tables)
+	return fixtures, nil, err
+}
+
+// parseJSONDocument parses JSON content into table fixtures and the table order of the ordered list format
+func parseJSONDocument(content []byte) (TableFixtures, []string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, nil, fmt.Errorf("failed to unmarshal JSON fixtures: %w", err)
+	}
+
+	var fixtures TableFixtures
+	var order []string
+	var err error
+	switch v := value.(type) {
+	case map[string]any:
+		fixtures, err = tableFixtures(v)
+	case []any:
+		fixtures, order, err = orderedTableFixtures(v)
+	default:
+		return nil, nil, fmt.Errorf("fixture file must be an object of table name to rows or a list of tables, got %T", value)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, records := range fixtures {
+		for _, record := range records {
+			for column, v := range record {
+				record[column] = convertJSONNumbers(v)
+			}
+		}
+	}
+	return fixtures, order, nil
+}
+
+// convertJSONNumbers replaces json.Number values with int64 or float64, recursing into objects and arrays
+func convertJSONNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = convertJSONNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = convertJSONNumbers(item)
+		}
+	}
+	return value
+}
+
+// tableFixtures checks that every table holds a list of rows and converts them to table fixtures
+func tableFixtures(tables map[string]any) (TableFixtures, error) {
+	fixtures := make(TableFixtures, len(tables))
+	for tableName, rows := range tables {
+		list, ok := rows.([]any)
+		if !ok && rows != nil {
+			return nil, fmt.Errorf("rows of table %s must be a list", tableName)
+		}
+		for i, row := range list {
+			record, ok := row.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("row %d of table %s must be a map of column to value", i, tableName)
+			}
+			fixtures[tableName] = append(fixtures[tableName], record)
+		}
+	}
+
+	return fixtures, nil
+}
+
+// orderedTableFixtures converts the entries of the ordered list format, returning the tables in listed order
+// Rows of a table listed more than once are appended to its first entry
+func orderedTableFixtures(entries []any) (TableFixtures, []string, error) {
+	tables := make(map[string]any)
+	var order []string
+	for i, entry := range entries {
+		fields, ok := entry.(map[string]any)
+		if !ok {
+			return nil, nil, fmt.Errorf("entry %d must be a map with table and rows", i)
+		}
+		// Sorted, so the error names the same key on every run
+		for _, key := range slices.Sorted(maps.Keys(fields)) {
+			if key != "table" && key != "rows" {
+				return nil, nil, fmt.Errorf("entry %d has unknown key %q, only table and rows are allowed", i, key)
+			}
+		}
+		tableName, ok := fields["table"].(string)
+		if !ok || tableName == "" {
+			return nil, nil, fmt.Errorf("entry %d must name its table", i)
+		}
+		rows, ok := fields["rows"].([]any)
+		if !ok && fields["rows"] != nil {
+			return nil, nil, fmt.Errorf("rows of entry %d (table %s) must be a list", i, tableName)
+		}
+
+		listed, seen := tables[tableName].([]any)
+		if !seen {
+			order = append(order, tableName)
+		}
+		tables[tableName] = append(listed, rows...)
+	}
+
+	fixtures, err := tableFixtures(tables)
+	if err != nil {
+		return nil, nil, err
+	}
+	return fixtures, order, nil
+}
+
+// nodeKindName describes the kind of a YAML node for error messages
+func nodeKindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		return "sequence"
+	case yaml.ScalarNode:
+		return "scalar"
+	case yaml.AliasNode:
+		return "alias"
+	case yaml.MappingNode:
+		return "map"
+	default:
+		return "document"
+	}
+}
+
+// decodeNode converts a YAML node into plain Go values, resolving value directives on scalars
+func decodeNode(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return decodeNode(node.Alias)
+	case yaml.MappingNode:
+		result := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, valueNode := node.Content[i], node.Content[i+1]
+			value, err := decodeNode(valueNode)
+			if err != nil {
+				return nil, err
+			}
+
+			// Merge keys (<<) copy entries that are not already set
+			if key.Tag == "!!merge" {
+				if err := mergeValue(result, value); err != nil {
+					return nil, fmt.Errorf("line %d: %w", key.Line, err)
+				}
+				continue
+			}
+			result[key.Value] = value
+		}
+		return result, nil
+	case yaml.SequenceNode:
+		result := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := decodeNode(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		return result, nil
+	case yaml.ScalarNode:
+		if directive, ok := valueDirectives[node.Tag]; ok {
+			value, err := directive(node.Value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", node.Line, err)
+			}
+			return value, nil
+		}
+		var value any
+		if err := node.Decode(&value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		return value, nil
+	default:
+		return nil, nil
+	}
+}
+
+// mergeValue applies a YAML merge key value (a map or a list of maps) to the target map
+func mergeValue(target map[string]any, value any) error {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if _, exists := target[key]; !exists {
+				target[key] = item
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if err := mergeValue(target, item); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("merge key value must be a map or a list of maps")
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package testkit
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMalformedFixtureFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "top-level scalar",
+			content: "users\n",
+			want:    "fixture file must be a map of table name to rows or a list of tables, got a scalar",
+		},
+		{
+			name:    "top-level list of rows",
+			content: "- id: 1\n  name: alice\n",
+			want:    `entry 0 has unknown key "id", only table and rows are allowed`,
+		},
+		{name: "rows not a list", content: "users:\n  id: 1\n", want: "rows of table users must be a list"},
+		{name: "row not a map", content: "users:\n  - alice\n", want: "row 0 of table users must be a map"},
+		{name: "invalid YAML", content: "users: [\n", want: "failed to unmarshal YAML fixtures"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, fake := newFakeManager(t, nil)
+			fixture := writeFixture(t, "broken.yml", tt.content)
+
+			err := fm.LoadYAMLFixtures(fixture)
+			if err == nil {
+				t.Fatal("LoadYAMLFixtures() error = nil, want a malformed file error")
+			}
+			if !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), fixture) {
+				t.Errorf("LoadYAMLFixtures() error = %q, want %q naming %s", err, tt.want, fixture)
+			}
+			if statements := fake.queryTexts(""); len(statements) != 0 {
+				t.Errorf("statements = %q, want none for a malformed file", statements)
+			}
+		})
+	}
+}
+
+func TestEmptyFixtureFile(t *testing.T) {
+	fixtures, err := YAMLParser{}.Parse([]byte("# nothing yet\n"))
+	if err != nil || len(fixtures) != 0 {
+		t.Errorf("Parse() = %v, %v, want no fixtures", fixtures, err)
+	}
+}
+
+func TestSequenceDirectives(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fm.ConfigureTable("invoices", []string{"number"})
+	fake.onQuery = func(string, []any) (*fakeRows, error) {
+		return &fakeRows{columns: []string{"number"}, rows: [][]any{{int64(1001)}}}, nil
+	}
+
+	fixture := writeFixture(t, "invoices.yml", `
+invoices:
+  - number: !nextval invoice_numbers
+    previous: !currval billing.invoice_numbers
+    total: 10
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queries("INSERT")
+	want := `INSERT INTO "invoices" ("number", "previous", "total") ` +
+		`VALUES (nextval('invoice_numbers'), currval('billing.invoice_numbers'), $1) RETURNING "number"`
+	if len(inserts) != 1 || inserts[0].Query != want {
+		t.Fatalf("inserts = %v, want %q", inserts, want)
+	}
+	if len(inserts[0].Args) != 1 || inserts[0].Args[0] != 10 {
+		t.Errorf("args = %v, want only the bound total", inserts[0].Args)
+	}
+	// The generated key is tracked even though the fixture did not know it
+	if keys := fm.GetInsertedKeys("invoices"); len(keys) != 1 || keys[0]["number"] != int64(1001) {
+		t.Errorf("GetInsertedKeys() = %v, want number 1001", keys)
+	}
+}
+
+func TestSequenceDirectiveRequiresName(t *testing.T) {
+	_, err := YAMLParser{}.Parse([]byte("invoices:\n  - number: !nextval\n"))
+	if err == nil || !strings.Contains(err.Error(), "!nextval requires a sequence name") {
+		t.Errorf("Parse() error = %v, want the missing sequence name", err)
+	}
+}
+
+func TestDefaultDirective(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.BatchSize = 10
+	fm, fake := newFakeManager(t, config)
+	fake.returnIDs()
+
+	fixture := writeFixture(t, "users.yml", `
+users:
+  - id: !default
+    name: alice
+    role: admin
+  - id: !default
+    name: bob
+    role: !default
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queries("INSERT")
+	want := `INSERT INTO "users" ("id", "name", "role") VALUES (DEFAULT, $1, $2), (DEFAULT, $3, DEFAULT) RETURNING "id"`
+	if len(inserts) != 1 || inserts[0].Query != want {
+		t.Fatalf("inserts = %v, want %q", inserts, want)
+	}
+	if args := inserts[0].Args; len(args) != 3 || args[0] != "alice" || args[1] != "admin" || args[2] != "bob" {
+		t.Errorf("args = %v, want only the bound values", args)
+	}
+	if keys := fm.GetInsertedKeys("users"); len(keys) != 2 {
+		t.Errorf("GetInsertedKeys() = %v, want the 2 generated ids", keys)
+	}
+}
+
+func TestYAMLMergeKeys(t *testing.T) {
+	fixtures, err := YAMLParser{}.Parse([]byte(`
+defaults: &defaults
+  - &user
+    active: true
+    role: member
+users:
+  - <<: *user
+    name: alice
+  - role: admin
+    <<: *user
+    name: bob
+  - <<: [*user, {team: core}]
+    name: carol
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	users := fixtures["users"]
+	tests := []struct {
+		row  int
+		want map[string]any
+	}{
+		{row: 0, want: map[string]any{"active": true, "role": "member", "name": "alice"}},
+		// Keys set in the row win over merged ones, wherever the merge key is
+		{row: 1, want: map[string]any{"active": true, "role": "admin", "name": "bob"}},
+		{row: 2, want: map[string]any{"active": true, "role": "member", "team": "core", "name": "carol"}},
+	}
+	for _, tt := range tests {
+		got := users[tt.row]
+		if len(got) != len(tt.want) {
+			t.Errorf("row %d = %v, want %v", tt.row, got, tt.want)
+			continue
+		}
+		for column, value := range tt.want {
+			if got[column] != value {
+				t.Errorf("row %d column %s = %v, want %v", tt.row, column, got[column], value)
+			}
+		}
+	}
+
+	// Rows merging the same anchor do not share their maps
+	users[0]["role"] = "changed"
+	if users[2]["role"] != "member" {
+		t.Error("merged rows share the anchored map")
+	}
+}
+
+func TestYAMLMergeKeyRejectsScalars(t *testing.T) {
+	_, err := YAMLParser{}.Parse([]byte("users:\n  - <<: 1\n    name: alice\n"))
+	if err == nil || !strings.Contains(err.Error(), "merge key value must be a map") {
+		t.Errorf("Parse() error = %v, want the invalid merge value", err)
+	}
+}
+
+func TestCustomParserForExtension(t *testing.T) {
+	// A made-up "table: name=value,..." line format
+	lines := FixtureParserFunc(func(content []byte) (TableFixtures, error) {
+		fixtures := make(TableFixtures)
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			tableName, fields, ok := strings.Cut(line, ": ")
+			if !ok {
+				return nil, fmt.Errorf("invalid line %q", line)
+			}
+			record := make(map[string]any)
+			for _, field := range strings.Split(fields, ",") {
+				column, value, _ := strings.Cut(field, "=")
+				record[column] = value
+			}
+			fixtures[tableName] = append(fixtures[tableName], record)
+		}
+		return fixtures, nil
+	})
+
+	config := DefaultFixtureConfig()
+	config.FileExtensions = append(config.FileExtensions, ".rows")
+	config.Parsers = map[string]FixtureParser{".rows": lines}
+	fm, fake := newFakeManager(t, config)
+	fake.returnIDs()
+
+	dir := t.TempDir()
+	writeFixtureIn(t, dir, "users.rows", "users: name=alice,role=admin\nusers: name=bob,role=member\n")
+	writeFixtureIn(t, dir, "tags.yml", "tags:\n  - name: go\n")
+	if err := fm.LoadFixturesFromDir(dir); err != nil {
+		t.Fatalf("LoadFixturesFromDir() error = %v", err)
+	}
+
+	if got := len(fake.queryTexts(`INSERT INTO "users"`)); got != 2 {
+		t.Errorf("user inserts = %d, want the 2 rows of the custom file", got)
+	}
+	if got := len(fake.queryTexts(`INSERT INTO "tags"`)); got != 1 {
+		t.Errorf("tag inserts = %d, want the YAML file loaded next to it", got)
+	}
+
+	writeFixtureIn(t, dir, "broken.rows", "no separator\n")
+	err := fm.LoadFixtureFile(filepath.Join(dir, "broken.rows"))
+	if err == nil || !strings.Contains(err.Error(), "broken.rows") || !strings.Contains(err.Error(), "invalid line") {
+		t.Errorf("LoadFixtureFile() error = %v, want the parser error naming the file", err)
+	}
+}
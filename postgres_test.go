@@ -0,0 +1,85 @@
+package testkit
+
+import (
+	"database/sql"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// envTestDatabase is the connection string of the PostgreSQL database used by the integration tests
+// The tests are skipped when it is not set, e.g. TESTKIT_TEST_DB=postgres://postgres@localhost/postgres?sslmode=disable
+const envTestDatabase = "TESTKIT_TEST_DB"
+
+// newPostgresDB connects to the integration test database within a schema of its own and runs the DDL statements
+// The schema is first on the search path, so unqualified names and current_schema() resolve to it; it is dropped
+// with everything in it when the test finishes
+func newPostgresDB(t testing.TB, ddl ...string) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(envTestDatabase)
+	if dsn == "" {
+		t.Skipf("Skipping test: %s is not set", envTestDatabase)
+	}
+	SkipIfNoDB(t, dsn)
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	schema := "testkit_" + strings.ReplaceAll(newUUID(), "-", "")[:12]
+	if _, err := admin.Exec("CREATE SCHEMA " + pq.QuoteIdentifier(schema)); err != nil {
+		admin.Close()
+		t.Fatalf("failed to create test schema: %v", err)
+	}
+
+	db, err := sql.Open("postgres", withSearchPath(t, dsn, schema))
+	if err != nil {
+		t.Fatalf("failed to open test schema: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if _, err := admin.Exec("DROP SCHEMA " + pq.QuoteIdentifier(schema) + " CASCADE"); err != nil {
+			t.Errorf("failed to drop test schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	for _, statement := range ddl {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("failed to run %q: %v", statement, err)
+		}
+	}
+	return db
+}
+
+// withSearchPath sets the search_path run-time parameter of a URL or key/value connection string
+func withSearchPath(t testing.TB, dsn, schema string) string {
+	t.Helper()
+
+	if !strings.Contains(dsn, "://") {
+		return dsn + " search_path=" + schema
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", envTestDatabase, err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// countTableRows returns the number of rows of a table of a test database
+func countTableRows(t testing.TB, db *sql.DB, table string) int {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + pq.QuoteIdentifier(table)).Scan(&count); err != nil {
+		t.Fatalf("failed to count rows of %s: %v", table, err)
+	}
+	return count
+}
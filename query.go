@@ -0,0 +1,123 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// buildWhereClause builds a parameterized condition list from column/value pairs
+// Columns are sorted so the generated SQL is deterministic, nil values are matched with IS NULL
+func buildWhereClause(dialect Dialect, where map[string]any, startParam int) (clause string, values []any) {
+	if len(where) == 0 {
+		return "", nil
+	}
+
+	columns := make([]string, 0, len(where))
+	for column := range where {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	conditions := make([]string, 0, len(columns))
+	paramCount := startParam
+	for _, column := range columns {
+		value := where[column]
+		if value == nil {
+			conditions = append(conditions, dialect.QuoteIdentifier(column)+" IS NULL")
+			continue
+		}
+		conditions = append(conditions, dialect.QuoteIdentifier(column)+" = "+dialect.Placeholder(paramCount))
+		values = append(values, value)
+		paramCount++
+	}
+
+	return strings.Join(conditions, " AND "), values
+}
+
+// queryRows runs a query and returns every row as a column/value map along with the column order
+func queryRows(ctx context.Context, q querier, query string, args ...any) ([]string, []map[string]any, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	var result []map[string]any
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			row[column] = normalizeScannedValue(values[i])
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+
+	return columns, result, nil
+}
+
+// normalizeScannedValue converts driver values into types that round-trip through fixtures
+// Drivers return text-like columns (numeric, uuid, json) as []byte, which is only kept for binary data
+func normalizeScannedValue(value any) any {
+	if b, ok := value.([]byte); ok {
+		if utf8.Valid(b) {
+			return string(b)
+		}
+		return append([]byte(nil), b...)
+	}
+	return value
+}
+
+// CountRows returns the number of rows in a table matching the given conditions
+func (fm *FixtureManager) CountRows(ctx context.Context, table string, where map[string]any) (int, error) {
+	return countRows(ctx, fm.db, fm.config.Dialect, table, where)
+}
+
+// countRows counts the rows of a table matching the given conditions using q
+func countRows(ctx context.Context, q querier, dialect Dialect, table string, where map[string]any) (int, error) {
+	query, args := countQuery(dialect, table, where)
+
+	var count int
+	if err := q.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count rows in table %s: %w", table, err)
+	}
+
+	return count, nil
+}
+
+// countQuery builds the query counting the rows of a table matching the given conditions
+func countQuery(dialect Dialect, table string, where map[string]any) (string, []any) {
+	//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", dialect.QuoteIdentifier(table))
+	clause, args := buildWhereClause(dialect, where, 1)
+	if clause != "" {
+		query += " WHERE " + clause
+	}
+	return query, args
+}
@@ -0,0 +1,182 @@
+package testkit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// aliasKey is the row key naming a row for alias references, it is removed before the row is inserted
+const aliasKey = "_alias"
+
+// aliasReference matches "$<table>.<alias>.<column>", the table may be schema qualified
+var aliasReference = regexp.MustCompile(`^\$([A-Za-z_][A-Za-z0-9_.]*)\.([A-Za-z0-9_-]+)\.([A-Za-z0-9_]+)$`)
+
+// aliasRegistry holds the aliased rows inserted by one load, by table and alias
+// Rows must be inserted before they are referenced, so parents need to come before their children
+type aliasRegistry struct {
+	mu   sync.Mutex
+	rows map[string]map[string]map[string]any
+}
+
+// newAliasRegistry creates an empty alias registry
+func newAliasRegistry() *aliasRegistry {
+	return &aliasRegistry{rows: make(map[string]map[string]map[string]any)}
+}
+
+// takeAlias returns the row's alias and the row without it
+func takeAlias(record map[string]any) (string, map[string]any) {
+	value, ok := record[aliasKey]
+	if !ok {
+		return "", record
+	}
+
+	rest := make(map[string]any, len(record)-1)
+	for column, v := range record {
+		if column != aliasKey {
+			rest[column] = v
+		}
+	}
+	alias, _ := value.(string)
+	return alias, rest
+}
+
+// add registers an inserted row under its alias
+func (a *aliasRegistry) add(tableName, alias string, record map[string]any) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if _, exists := a.rows[tableName][alias]; exists {
+		return fmt.Errorf("alias %s is already used in table %s", alias, tableName)
+	}
+	if a.rows[tableName] == nil {
+		a.rows[tableName] = make(map[string]map[string]any)
+	}
+	a.rows[tableName][alias] = record
+	return nil
+}
+
+// resolveReferences replaces "$<table>.<alias>.<column>" values with the column of the aliased row
+// Referenced column names go through the mapper like the row's own columns
+func (a *aliasRegistry) resolveReferences(record map[string]any, mapper func(string) string) (map[string]any, error) {
+	var resolved map[string]any
+	for column, value := range record {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		match := aliasReference.FindStringSubmatch(s)
+		if match == nil {
+			continue
+		}
+
+		target, err := a.lookup(match[1], match[2], match[3], mapper)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", column, err)
+		}
+		if resolved == nil {
+			resolved = copyRecord(record)
+		}
+		resolved[column] = target
+	}
+
+	if resolved == nil {
+		return record, nil
+	}
+	return resolved, nil
+}
+
+// lookup returns a column of an aliased row
+func (a *aliasRegistry) lookup(tableName, alias, column string, mapper func(string) string) (any, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	row, ok := a.rows[tableName][alias]
+	if !ok {
+		return nil, fmt.Errorf("no row aliased %s in table %s, aliased rows must be inserted before they are referenced", alias, tableName)
+	}
+	if mapper != nil {
+		column = mapper(column)
+	}
+
+	value, ok := row[column]
+	if !ok {
+		return nil, fmt.Errorf("row %s.%s has no column %s", tableName, alias, column)
+	}
+	if _, isExpression := value.(sqlExpression); isExpression {
+		return nil, fmt.Errorf("column %s of row %s.%s is computed by the database and cannot be referenced", column, tableName, alias)
+	}
+	return value, nil
+}
+
+// copyRecord returns a shallow copy of a row, used before modifying rows that belong to the parsed fixtures
+func copyRecord(record map[string]any) map[string]any {
+	copied := make(map[string]any, len(record))
+	for column, value := range record {
+		copied[column] = value
+	}
+	return copied
+}
+
+// selfReferencePrefix marks a value copied from another column of the same row, e.g. "$self:username"
+const selfReferencePrefix = "$self:"
+
+// resolveSelfReferences replaces "$self:<column>" values with the value of that column in the same row
+// Referenced column names go through the mapper like the row's own columns, chained references are followed
+func resolveSelfReferences(record map[string]any, mapper func(string) string) (map[string]any, error) {
+	var resolved map[string]any
+	for column, value := range record {
+		if _, ok := selfReference(value); !ok {
+			continue
+		}
+		if resolved == nil {
+			resolved = copyRecord(record)
+		}
+
+		target, err := followSelfReference(record, column, mapper)
+		if err != nil {
+			return nil, err
+		}
+		resolved[column] = target
+	}
+
+	if resolved == nil {
+		return record, nil
+	}
+	return resolved, nil
+}
+
+// followSelfReference resolves the value of a column, following chained references and detecting cycles
+func followSelfReference(record map[string]any, column string, mapper func(string) string) (any, error) {
+	visited := map[string]bool{column: true}
+	value := record[column]
+	for {
+		ref, ok := selfReference(value)
+		if !ok {
+			return value, nil
+		}
+		if mapper != nil {
+			ref = mapper(ref)
+		}
+
+		next, exists := record[ref]
+		if !exists {
+			return nil, fmt.Errorf("column %s references missing column %s", column, ref)
+		}
+		if visited[ref] {
+			return nil, fmt.Errorf("column %s has a circular self reference through %s", column, ref)
+		}
+		visited[ref] = true
+		value = next
+	}
+}
+
+// selfReference returns the column referenced by a "$self:<column>" value
+func selfReference(value any) (string, bool) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, selfReferencePrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(s, selfReferencePrefix), true
+}
@@ -0,0 +1,107 @@
+package testkit
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// RequestRetryPolicy controls retries of the runner's test requests on server errors and connection failures
+// 4xx responses are never retried
+type RequestRetryPolicy struct {
+	// Total number of attempts including the first one (defaults to 3)
+	MaxAttempts int
+	// Delay before the first retry, doubled for every further retry (defaults to 100ms)
+	Backoff time.Duration
+	// Upper bound for the delay between retries (defaults to 2s)
+	MaxBackoff time.Duration
+	// Also retry non-idempotent methods such as POST and PATCH
+	RetryNonIdempotent bool
+}
+
+// withDefaults returns a copy of the policy with unset fields defaulted
+func (p RequestRetryPolicy) withDefaults() RequestRetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 2 * time.Second
+	}
+	return p
+}
+
+// RetryRoundTripper retries requests that fail with a connection error or a 5xx response
+// Requests with a body are only retried when the body can be replayed (http.Request.GetBody)
+type RetryRoundTripper struct {
+	// Next is the underlying transport (defaults to http.DefaultTransport)
+	Next http.RoundTripper
+	// Policy controls the number of attempts and the backoff
+	Policy RequestRetryPolicy
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *RetryRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	policy := t.Policy.withDefaults()
+
+	if !t.retryable(req, policy) {
+		return next.RoundTrip(req)
+	}
+
+	delay := policy.Backoff
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		resp, err := next.RoundTrip(attemptReq)
+		if attempt >= policy.MaxAttempts || (err == nil && resp.StatusCode < http.StatusInternalServerError) {
+			return resp, err
+		}
+		if err == nil {
+			// Drain the discarded response so the connection can be reused
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, policy.MaxBackoff)
+	}
+}
+
+// retryable reports whether the request may be sent more than once
+func (t *RetryRoundTripper) retryable(req *http.Request, policy RequestRetryPolicy) bool {
+	if policy.MaxAttempts <= 1 {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	return policy.RetryNonIdempotent || isIdempotent(req.Method)
+}
+
+// isIdempotent reports whether the HTTP method is idempotent
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
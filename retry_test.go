@@ -0,0 +1,231 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// scriptedTransport answers the attempts of a request in turn, a zero status answers with a connection error,
+// and records the request bodies it received
+type scriptedTransport struct {
+	statuses []int
+	attempts int
+	bodies   []string
+}
+
+// RoundTrip implements http.RoundTripper
+func (s *scriptedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		s.bodies = append(s.bodies, string(body))
+	}
+	status := s.statuses[min(s.attempts, len(s.statuses)-1)]
+	s.attempts++
+	if status == 0 {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+func TestRetryRoundTripper(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		body      string
+		noGetBody bool
+		policy    RequestRetryPolicy
+		statuses  []int
+		wantTries int
+		want      int
+		wantErr   bool
+	}{
+		{name: "GET retried on 5xx", method: http.MethodGet, statuses: []int{503, 500, 200}, wantTries: 3, want: 200},
+		{name: "GET gives up", method: http.MethodGet, statuses: []int{503}, wantTries: 3, want: 503},
+		{name: "4xx not retried", method: http.MethodGet, statuses: []int{404, 200}, wantTries: 1, want: 404},
+		{name: "dial error retried", method: http.MethodPut, body: "{}", statuses: []int{0, 204}, wantTries: 2, want: 204},
+		{name: "dial error returned", method: http.MethodDelete, statuses: []int{0}, wantTries: 3, wantErr: true},
+		{name: "POST not retried", method: http.MethodPost, body: "{}", statuses: []int{503, 201}, wantTries: 1, want: 503},
+		{
+			name:      "POST retried when allowed",
+			method:    http.MethodPost,
+			body:      `{"name":"alice"}`,
+			policy:    RequestRetryPolicy{RetryNonIdempotent: true},
+			statuses:  []int{502, 201},
+			wantTries: 2,
+			want:      201,
+		},
+		{
+			name:      "body that cannot be replayed",
+			method:    http.MethodPut,
+			body:      "{}",
+			noGetBody: true,
+			statuses:  []int{503, 204},
+			wantTries: 1,
+			want:      503,
+		},
+		{
+			name:      "single attempt",
+			method:    http.MethodGet,
+			policy:    RequestRetryPolicy{MaxAttempts: 1},
+			statuses:  []int{503, 200},
+			wantTries: 1,
+			want:      503,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &scriptedTransport{statuses: tt.statuses}
+			policy := tt.policy
+			policy.Backoff = time.Millisecond
+			retry := &RetryRoundTripper{Next: transport, Policy: policy}
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req, err := http.NewRequestWithContext(context.Background(), tt.method, "http://localhost/users", body)
+			if err != nil {
+				t.Fatalf("failed to create request: %v", err)
+			}
+			if tt.noGetBody {
+				req.GetBody = nil
+			}
+
+			resp, err := retry.RoundTrip(req)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("RoundTrip() error = nil, want the connection error")
+				}
+			} else if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			} else if resp.StatusCode != tt.want {
+				t.Errorf("RoundTrip() status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if transport.attempts != tt.wantTries {
+				t.Errorf("attempts = %d, want %d", transport.attempts, tt.wantTries)
+			}
+			for i, received := range transport.bodies {
+				if tt.body != "" && received != tt.body {
+					t.Errorf("attempt %d body = %q, want the replayed %q", i+1, received, tt.body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryRoundTripperStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	retry := &RetryRoundTripper{
+		Next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			attempts++
+			cancel()
+			return &http.Response{StatusCode: 503, Body: http.NoBody, Request: req}, nil
+		}),
+		Policy: RequestRetryPolicy{MaxAttempts: 5, Backoff: time.Hour},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/users", http.NoBody)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if _, err := retry.RoundTrip(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("RoundTrip() error = %v, want context.Canceled", err)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want no retry after the context is done", attempts)
+	}
+}
+
+func TestRequestRetryPolicyBackoff(t *testing.T) {
+	policy := RequestRetryPolicy{}.withDefaults()
+	if policy.MaxAttempts != 3 || policy.Backoff != 100*time.Millisecond || policy.MaxBackoff != 2*time.Second {
+		t.Errorf("withDefaults() = %+v, want 3 attempts, 100ms backoff and 2s max backoff", policy)
+	}
+
+	// Delays double from Backoff and are capped at MaxBackoff: 1ms, 2ms, 3ms, 3ms
+	var sent []time.Time
+	retry := &RetryRoundTripper{
+		Next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			sent = append(sent, time.Now())
+			return &http.Response{StatusCode: 500, Body: http.NoBody, Request: req}, nil
+		}),
+		Policy: RequestRetryPolicy{MaxAttempts: 5, Backoff: time.Millisecond, MaxBackoff: 3 * time.Millisecond},
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "http://localhost/users", http.NoBody)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if _, err := retry.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip() error = %v", err)
+	}
+	if len(sent) != 5 {
+		t.Fatalf("attempts = %d, want 5", len(sent))
+	}
+	delays := []time.Duration{time.Millisecond, 2 * time.Millisecond, 3 * time.Millisecond, 3 * time.Millisecond}
+	for i, want := range delays {
+		if waited := sent[i+1].Sub(sent[i]); waited < want {
+			t.Errorf("delay before attempt %d = %v, want at least %v", i+2, waited, want)
+		}
+	}
+}
+
+func TestIsIdempotent(t *testing.T) {
+	for method, want := range map[string]bool{
+		"":                 true,
+		http.MethodGet:     true,
+		http.MethodHead:    true,
+		http.MethodOptions: true,
+		http.MethodPut:     true,
+		http.MethodDelete:  true,
+		http.MethodPost:    false,
+		http.MethodPatch:   false,
+	} {
+		if got := isIdempotent(method); got != want {
+			t.Errorf("isIdempotent(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestRunnerRetriesRequests(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"ok": true}`)
+	}))
+	t.Cleanup(server.Close)
+
+	runner, _ := newFakeRunner(t, &RunnerConfig{
+		BaseURL:      server.URL,
+		RequestRetry: &RequestRetryPolicy{Backoff: time.Millisecond},
+	})
+	var body struct{ OK bool }
+	if resp := runner.GetJSON(t, "/users", &body); resp.StatusCode != http.StatusOK || !body.OK {
+		t.Errorf("GetJSON() = %d %+v, want the retried request to succeed", resp.StatusCode, body)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+}
@@ -2,22 +2,49 @@ package testkit
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	_ "github.com/lib/pq" // Import the PostgreSQL driver
+	"google.golang.org/grpc"
 )
 
 // DefaultTimeout is the default timeout for HTTP requests
 const DefaultTimeout = time.Second * 10
 
+// PrimaryDatabase is the name of the database configured by RunnerConfig.DBConnectionString
+const PrimaryDatabase = "primary"
+
+// EnvHold is the environment variable that, when set to 1, makes RunWithTesting keep the seeded state
+// after the tests finished and wait for SIGINT or SIGTERM before cleaning up
+const EnvHold = "TESTKIT_HOLD"
+
 // Global runner instance that can be accessed by tests
+// RunWithTesting sets it before the tests run and leaves it in place, cleaned up, once they finished
+// Harnesses running several suites in one process call ResetRunner between them
 var Runner *TestRunner
 
+// ResetRunner cleans up the global Runner if that has not happened yet and sets it to nil
+func ResetRunner() {
+	if Runner != nil {
+		Runner.Cleanup()
+	}
+	Runner = nil
+}
+
 // AppStarter defines the interface for starting and stopping an application
 type AppStarter interface {
 	// Start starts the application
@@ -26,34 +53,161 @@ type AppStarter interface {
 	Stop(ctx context.Context) error
 }
 
+// HealthEndpoint selects which health endpoints the runner waits on before running tests
+type HealthEndpoint int
+
+const (
+	// WaitHealthCheck waits on HealthCheckPath (default)
+	WaitHealthCheck HealthEndpoint = iota
+	// WaitLiveness waits on LivenessPath
+	WaitLiveness
+	// WaitReadiness waits on ReadinessPath
+	WaitReadiness
+	// WaitLivenessAndReadiness waits on LivenessPath, then on ReadinessPath
+	WaitLivenessAndReadiness
+	// WaitGRPC waits on the gRPC health service of GRPCAddress instead of an HTTP endpoint
+	WaitGRPC
+)
+
 // RunnerConfig holds configuration for the test runner
 type RunnerConfig struct {
 	// Database connection string
 	DBConnectionString string
+	// database/sql driver used for all databases (defaults to "postgres")
+	// The driver must be imported by the test binary, fixture SQL follows its dialect, e.g. ? placeholders for "sqlite3"
+	DriverName string
+	// Additional databases by name (name to connection string), the primary database is always available
+	Databases map[string]string
+	// Create a dedicated primary database for the run from this name template and drop it at cleanup
+	// The template receives DatabaseNameData, e.g. "test_{{.Pkg}}_{{.Rand}}"
+	DatabaseNameTemplate string
+	// Connection string used to create and drop the dedicated database (defaults to DBConnectionString)
+	AdminDSN string
+	// Maximum number of open connections of each database (defaults to 10), a negative value removes the limit
+	MaxOpenConns int
+	// Maximum number of idle connections kept by each database (defaults to 2), a negative value keeps none
+	MaxIdleConns int
+	// Maximum time a connection is reused, 0 reuses connections forever
+	ConnMaxLifetime time.Duration
+	// Connection string of a read replica of the primary database, exposed through GetReadDB
+	// Fixtures are always loaded into and cleaned up from the primary
+	ReplicaDSN string
 	// Base URL for the API
 	BaseURL string
+	// Address of the application's gRPC server, e.g. "localhost:9090", exposed through GetGRPCConn
+	GRPCAddress string
+	// Service name checked with grpc.health.v1.Health/Check when WaitFor is WaitGRPC, empty checks the whole server
+	GRPCHealthService string
+	// Dial options of the gRPC connection (defaults to TLSConfig credentials, or plaintext without TLSConfig)
+	GRPCDialOptions []grpc.DialOption
+	// Path prefix prepended to health check and request paths, e.g. "/api"
+	PathPrefix string
 	// Path to fixtures directory
 	FixturesDir string
-	// Application to start
+	// Configuration of the fixture managers of all databases (defaults to DefaultFixtureConfig)
+	// Each manager gets its own copy, e.g. set ExpandEnv to reference variables loaded with LoadEnvFiles
+	FixtureConfig *FixtureConfig
+	// Directory of .sql migrations applied to the primary database by NewTestRunner, see ApplyMigrations
+	MigrationsDir string
+	// Migrates the primary database in NewTestRunner, after MigrationsDir, e.g. with a migration library
+	MigrateFunc func(db *sql.DB) error
+	// Application to start, leave it and BaseURL empty for a data-only run that only seeds the database
 	App AppStarter
 	// Health check endpoint path (defaults to "/v1/health/liveness")
 	HealthCheckPath string
+	// Liveness endpoint path, used when WaitFor selects liveness
+	LivenessPath string
+	// Readiness endpoint path, used when WaitFor selects readiness
+	ReadinessPath string
+	// Health endpoints to wait on (defaults to WaitHealthCheck)
+	WaitFor HealthEndpoint
+	// Readiness check polled instead of the WaitFor endpoints, e.g. to wait for a message consumer to connect
+	// It is retried like the HTTP health check until it returns nil, each call is limited to DefaultTimeout
+	ReadinessFunc func(ctx context.Context) error
 	// Maximum number of attempts to wait for server (defaults to 30)
 	MaxWaitAttempts int
+	// Maximum time to wait for the server, takes precedence over MaxWaitAttempts when set
+	ReadyTimeout time.Duration
+	// Delay after the first failed readiness attempt (defaults to 1s)
+	PollInterval time.Duration
+	// Factor growing the delay after each further failed attempt, e.g. 2 for exponential backoff (defaults to 1)
+	PollMultiplier float64
+	// Upper bound of the delay grown by PollMultiplier, 0 leaves it unbounded
+	MaxPollInterval time.Duration
+	// Health check status codes considered ready (defaults to 200)
+	HealthCheckStatusCodes []int
+	// Headers sent with every health check request
+	HealthCheckHeaders map[string]string
+	// HTTP method used for health checks (defaults to GET)
+	HealthCheckMethod string
+	// Fail the suite if loaded tables still contain rows after cleanup
+	StrictCleanup bool
+	// Run Cleanup and exit when the process receives SIGINT or SIGTERM, so an interrupted run leaves nothing behind
+	HandleSignals bool
+	// Hard deadline for Cleanup, a step still running then is abandoned and reported, 0 waits indefinitely
+	CleanupTimeout time.Duration
+	// Time the application gets to stop during Cleanup, after which cleanup moves on (defaults to DefaultTimeout)
+	ShutdownTimeout time.Duration
+	// Fail the suite if database connections are still in use at cleanup, e.g. unclosed rows or transactions
+	DetectLeaks bool
+	// Log every request and response made by the runner's HTTP client
+	DebugHTTP bool
+	// Headers redacted from HTTP debug logs (defaults to DefaultRedactedHeaders)
+	RedactHeaders []string
+	// TLS configuration for readiness probes and test traffic, e.g. a custom CA pool for self-signed certificates
+	TLSConfig *tls.Config
+	// Timeout of a test request including its retries (defaults to DefaultTimeout)
+	RequestTimeout time.Duration
+	// Retry test requests on 5xx responses and connection errors, nil disables retries
+	RequestRetry *RequestRetryPolicy
+	// Client used verbatim for test traffic, e.g. with a cookie jar or a CheckRedirect that stops at redirects
+	// DebugHTTP, TLSConfig, RequestTimeout and RequestRetry are not applied to it, readiness probes keep the default
+	// It is also returned by GetHTTPClient in data-only mode, e.g. for tests calling external services
+	HTTPClient *http.Client
+	// Called by Run after fixtures are loaded and before the tests, an error skips the tests and fails the run
+	BeforeAll func(*TestRunner) error
+	// Called by Run after the tests and before cleanup
+	AfterAll func(*TestRunner)
+	// Called as the last step of Cleanup, once nothing uses the databases anymore, e.g. to stop a database container
+	AfterCleanup func()
 }
 
 // TestRunner manages the test environment and execution
 type TestRunner struct {
-	config         *RunnerConfig
-	db             *sql.DB
-	httpClient     *http.Client
+	config     *RunnerConfig
+	db         *sql.DB
+	httpClient *http.Client
+	// Client used for readiness probes, without request retries
+	probeClient    *http.Client
 	fixtureManager *FixtureManager
-	cleanup        func()
+	// All databases and their fixture managers by name, including the primary one
+	dbs             map[string]*sql.DB
+	fixtureManagers map[string]*FixtureManager
+	// Read replica connection, nil unless ReplicaDSN is configured
+	readDB *sql.DB
+	// gRPC connection, nil unless GRPCAddress is configured
+	grpcConn *grpc.ClientConn
+	// Connection string of the primary database, which differs from the configuration for dedicated databases
+	primaryDSN string
+	// Runs the cleanup steps, reporting each step as it starts
+	cleanup     func(ctx context.Context, step func(name string)) error
+	cleanupOnce sync.Once
+	// Set once Cleanup ran
+	cleanedUp atomic.Bool
+	// Stops the HandleSignals handler, nil when it is not installed
+	stopSignals func()
+	// Error reported by the strict cleanup and leak checks or the cleanup timeout
+	cleanupErr error
 }
 
 // RunWithTesting runs tests with the given testing.M and configuration
 // This is a convenience function that handles creating the runner, running tests, and cleanup
 func RunWithTesting(m *testing.M, config *RunnerConfig) {
+	// A live runner left behind by an earlier suite would keep its databases and application running
+	if Runner != nil && !Runner.cleanedUp.Load() {
+		log.Printf("Warning: RunWithTesting replaces a runner that was not cleaned up, call ResetRunner between suites")
+	}
+
 	// Create runner
 	var err error
 	Runner, err = NewTestRunner(config)
@@ -62,8 +216,26 @@ func RunWithTesting(m *testing.M, config *RunnerConfig) {
 	}
 	defer Runner.Cleanup()
 
-	// Run tests
-	code := Runner.Run(m)
+	// Run tests, a setup failure still goes through the cleanup below
+	code, runErr := Runner.RunE(m)
+
+	// Keep the seeded state around for inspection when requested
+	if os.Getenv(EnvHold) == "1" {
+		// The interrupt ending the hold must reach the cleanup below, not the signal handler
+		if Runner.stopSignals != nil {
+			Runner.stopSignals()
+		}
+		holdUntilInterrupted()
+	}
+
+	// Clean up before checking for leftover rows
+	Runner.Cleanup()
+	if err := Runner.CleanupError(); err != nil {
+		panic(fmt.Errorf("cleanup checks failed: %w", err))
+	}
+	if runErr != nil {
+		panic(runErr)
+	}
 
 	// Exit with the test result code
 	if code != 0 {
@@ -71,8 +243,47 @@ func RunWithTesting(m *testing.M, config *RunnerConfig) {
 	}
 }
 
+// holdUntilInterrupted blocks until the process receives SIGINT or SIGTERM
+// Use it with go test -timeout 0, otherwise the test binary is killed once the timeout expires
+func holdUntilInterrupted() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	log.Printf("%s=1: tests finished, database state is kept until interrupted (Ctrl-C to clean up)", EnvHold)
+	sig := <-signals
+	log.Printf("Received %s, cleaning up", sig)
+}
+
+// handleSignals cleans up and exits when the process is interrupted, until Cleanup runs on the normal path
+// go test delivers Ctrl-C to the test binary too, so the handler runs while the go command waits for it to exit
+func (r *TestRunner) handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	r.stopSignals = sync.OnceFunc(func() {
+		signal.Stop(signals)
+		close(done)
+	})
+
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("Received %s, cleaning up before exiting", sig)
+			r.Cleanup()
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+}
+
 // NewTestRunner creates a new test runner with the given configuration
+// The configuration is copied, defaults are applied to the copy returned by GetConfig
 func NewTestRunner(config *RunnerConfig) (*TestRunner, error) {
+	// Leave the caller's struct untouched, it may be reused for another runner
+	effective := *config
+	config = &effective
+
 	// Set defaults for optional fields
 	if config.HealthCheckPath == "" {
 		config.HealthCheckPath = "/v1/health/liveness"
@@ -80,46 +291,172 @@ func NewTestRunner(config *RunnerConfig) (*TestRunner, error) {
 	if config.MaxWaitAttempts <= 0 {
 		config.MaxWaitAttempts = 30
 	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = time.Second
+	}
+	if config.RequestTimeout <= 0 {
+		config.RequestTimeout = DefaultTimeout
+	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = DefaultTimeout
+	}
+	if config.MaxOpenConns == 0 {
+		config.MaxOpenConns = 10
+	}
+	if config.MaxIdleConns == 0 {
+		config.MaxIdleConns = 2
+	}
+	if config.DriverName == "" {
+		config.DriverName = "postgres"
+	}
+	if config.DatabaseNameTemplate != "" && config.DriverName != "postgres" {
+		return nil, fmt.Errorf("DatabaseNameTemplate requires the postgres driver, got %q", config.DriverName)
+	}
 
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: DefaultTimeout,
+	// Create HTTP clients, unless the run only seeds the database, which only keeps a supplied client
+	var client, probeClient *http.Client
+	if config.dataOnly() {
+		client = config.HTTPClient
+	} else {
+		client, probeClient = newHTTPClients(config)
+	}
+
+	// Create a dedicated database for this run when configured
+	primaryDSN := config.DBConnectionString
+	var ephemeral *ephemeralDatabase
+	if config.DatabaseNameTemplate != "" {
+		var err error
+		if ephemeral, err = createEphemeralDatabase(context.Background(), config); err != nil {
+			return nil, err
+		}
+		primaryDSN = ephemeral.dsn(config.DBConnectionString)
 	}
 
-	// Connect to database
-	db, err := sql.Open("postgres", config.DBConnectionString)
+	// Connect to databases
+	dbs, err := openDatabases(config, primaryDSN)
+	var readDB *sql.DB
+	if err == nil && config.ReplicaDSN != "" {
+		if readDB, err = openDatabase(config, "replica", config.ReplicaDSN); err != nil {
+			for _, db := range dbs {
+				db.Close()
+			}
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		if ephemeral != nil {
+			if dropErr := ephemeral.drop(context.Background()); dropErr != nil {
+				log.Printf("Warning: %v", dropErr)
+			}
+		}
+		return nil, err
 	}
 
-	// Initialize fixture manager
-	fixtureManager := NewFixtureManager(db)
+	// Connection usage before any test ran, compared at cleanup to detect leaks
+	baseline := connectionStats(dbs)
+
+	// Initialize fixture managers
+	fixtureManagers := make(map[string]*FixtureManager, len(dbs))
+	for name, db := range dbs {
+		fixtureConfig := DefaultFixtureConfig()
+		if config.FixtureConfig != nil {
+			copied := *config.FixtureConfig
+			fixtureConfig = &copied
+		}
+		fixtureManagers[name] = NewFixtureManagerWithConfig(db, fixtureConfig)
+		fixtureManagers[name].SetDialect(dialectForDriver(config.DriverName))
+	}
 
 	// Create test runner
 	runner := &TestRunner{
-		config:         config,
-		db:             db,
-		httpClient:     client,
-		fixtureManager: fixtureManager,
-		cleanup: func() {
-			if fixtureManager != nil {
-				if err := fixtureManager.CleanupFixtures(); err != nil {
-					log.Printf("Warning: failed to cleanup fixtures: %v", err)
-				}
+		config:          config,
+		db:              dbs[PrimaryDatabase],
+		httpClient:      client,
+		probeClient:     probeClient,
+		fixtureManager:  fixtureManagers[PrimaryDatabase],
+		dbs:             dbs,
+		fixtureManagers: fixtureManagers,
+		readDB:          readDB,
+		primaryDSN:      primaryDSN,
+	}
+	runner.cleanup = func(ctx context.Context, step func(name string)) error {
+		var residue []error
+		for name, fixtureManager := range fixtureManagers {
+			step("cleaning up fixtures in the " + name + " database")
+			if err := fixtureManager.CleanupFixturesContext(ctx); err != nil {
+				log.Printf("Warning: failed to cleanup fixtures in %s database: %v", name, err)
 			}
-			if db != nil {
-				if err := db.Close(); err != nil {
-					log.Printf("Warning: failed to close database connection: %v", err)
-				}
+			if err := fixtureManager.DropSnapshot(ctx); err != nil {
+				log.Printf("Warning: failed to drop snapshot in %s database: %v", name, err)
 			}
-			if config.App != nil {
-				if err := config.App.Stop(context.Background()); err != nil {
-					log.Printf("Warning: failed to stop application: %v", err)
+			if config.StrictCleanup {
+				if err := fixtureManager.VerifyTablesEmpty(ctx); err != nil {
+					residue = append(residue, fmt.Errorf("%s database: %w", name, err))
 				}
 			}
-		},
+		}
+		if config.DetectLeaks {
+			if err := checkConnectionLeaks(baseline, connectionStats(dbs)); err != nil {
+				residue = append(residue, err)
+			}
+		}
+		step("closing database connections")
+		for name, db := range dbs {
+			if err := db.Close(); err != nil {
+				log.Printf("Warning: failed to close %s database connection: %v", name, err)
+			}
+		}
+		if readDB != nil {
+			if err := readDB.Close(); err != nil {
+				log.Printf("Warning: failed to close replica database connection: %v", err)
+			}
+		}
+		if runner.grpcConn != nil {
+			step("closing the gRPC connection")
+			if err := runner.grpcConn.Close(); err != nil {
+				log.Printf("Warning: failed to close gRPC connection: %v", err)
+			}
+		}
+		if config.App != nil {
+			step("stopping the application")
+			if err := stopApp(ctx, config.App, config.ShutdownTimeout); err != nil {
+				log.Printf("Warning: failed to stop application: %v", err)
+			}
+		}
+		// Drop the dedicated database last, once nothing is connected to it
+		if ephemeral != nil {
+			step("dropping the dedicated database")
+			if err := ephemeral.drop(ctx); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+		if config.AfterCleanup != nil {
+			step("running AfterCleanup")
+			config.AfterCleanup()
+		}
+		return errors.Join(residue...)
+	}
+
+	if config.GRPCAddress != "" {
+		conn, err := newGRPCConn(config)
+		if err != nil {
+			runner.Cleanup()
+			return nil, err
+		}
+		runner.grpcConn = conn
+	}
+
+	// Migrations come before the application starts and before Run loads the fixtures
+	if err := runner.migrate(); err != nil {
+		runner.Cleanup()
+		return nil, err
+	}
+
+	if config.HandleSignals {
+		runner.handleSignals()
 	}
 
+	log.Print(runner.Describe())
+
 	// Start the application if provided
 	if config.App != nil {
 		// Start application in a goroutine
@@ -130,74 +467,361 @@ func NewTestRunner(config *RunnerConfig) (*TestRunner, error) {
 		}()
 
 		// Wait for the server to be ready
-		healthCheckURL := fmt.Sprintf("%s%s", config.BaseURL, config.HealthCheckPath)
-		if err := runner.waitForServer(healthCheckURL, config.MaxWaitAttempts); err != nil {
+		if err := runner.waitUntilReady(); err != nil {
 			runner.Cleanup()
-			return nil, fmt.Errorf("server did not start in time: %w", err)
+			return nil, err
 		}
 	}
 
 	return runner, nil
 }
 
+// stopApp stops the application, giving up after timeout even when Stop ignores its context
+// A Stop call still running then is abandoned, so a hung shutdown cannot block teardown
+func stopApp(ctx context.Context, app AppStarter, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Stop(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("application did not stop within %s: %w", timeout, ctx.Err())
+	}
+}
+
+// dataOnly reports whether the run only seeds the database, without an application, base URL or gRPC address
+// Such runs skip health checks and have no HTTP client unless HTTPClient is set
+func (c *RunnerConfig) dataOnly() bool {
+	return c.App == nil && c.BaseURL == "" && c.GRPCAddress == ""
+}
+
+// healthCheckPaths returns the HTTP endpoint paths to wait on, in order
+// WaitGRPC needs no HTTP endpoint and returns none
+func (c *RunnerConfig) healthCheckPaths() ([]string, error) {
+	var paths []string
+	switch c.WaitFor {
+	case WaitGRPC:
+		if c.GRPCAddress == "" {
+			return nil, fmt.Errorf("GRPCAddress is not configured for the WaitGRPC mode")
+		}
+		return nil, nil
+	case WaitHealthCheck:
+		paths = []string{c.HealthCheckPath}
+	case WaitLiveness:
+		paths = []string{c.LivenessPath}
+	case WaitReadiness:
+		paths = []string{c.ReadinessPath}
+	case WaitLivenessAndReadiness:
+		paths = []string{c.LivenessPath, c.ReadinessPath}
+	default:
+		return nil, fmt.Errorf("unknown health endpoint selection %d", c.WaitFor)
+	}
+
+	for _, path := range paths {
+		if path == "" {
+			return nil, fmt.Errorf("health endpoint path is not configured for the selected WaitFor mode")
+		}
+	}
+	return paths, nil
+}
+
+// newHTTPClients builds the client used for test traffic and the one used for readiness probes
+// Both share the transport, only test traffic is retried since probes have their own retry loop
+// A configured HTTPClient replaces the test traffic client as is
+func newHTTPClients(config *RunnerConfig) (client, probeClient *http.Client) {
+	var transport http.RoundTripper
+	if config.TLSConfig != nil {
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		base.TLSClientConfig = config.TLSConfig
+		transport = base
+	}
+	if config.DebugHTTP {
+		transport = &LoggingRoundTripper{Next: transport, RedactHeaders: config.RedactHeaders}
+	}
+	probeClient = &http.Client{
+		Timeout:   DefaultTimeout,
+		Transport: transport,
+	}
+
+	if config.RequestRetry != nil {
+		transport = &RetryRoundTripper{Next: transport, Policy: *config.RequestRetry}
+	}
+	client = config.HTTPClient
+	if client == nil {
+		client = &http.Client{
+			Timeout:   config.RequestTimeout,
+			Transport: transport,
+		}
+	}
+
+	return client, probeClient
+}
+
+// openDatabases opens the primary database and every additional configured database
+func openDatabases(config *RunnerConfig, primaryDSN string) (map[string]*sql.DB, error) {
+	dsns := map[string]string{PrimaryDatabase: primaryDSN}
+	for name, dsn := range config.Databases {
+		if name == PrimaryDatabase {
+			return nil, fmt.Errorf("database name %q is reserved for DBConnectionString", PrimaryDatabase)
+		}
+		dsns[name] = dsn
+	}
+
+	dbs := make(map[string]*sql.DB, len(dsns))
+	for name, dsn := range dsns {
+		db, err := openDatabase(config, name, dsn)
+		if err != nil {
+			for _, opened := range dbs {
+				opened.Close()
+			}
+			return nil, err
+		}
+		dbs[name] = db
+	}
+
+	return dbs, nil
+}
+
+// connectTimeout bounds how long NewTestRunner waits for each database to answer a ping
+const connectTimeout = 5 * time.Second
+
+// openDatabase opens a database with the configured pool settings and pings it
+// sql.Open alone does not connect, the ping reports a wrong connection string before any fixture is loaded
+func openDatabase(config *RunnerConfig, name, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(config.DriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s database: %w", name, err)
+	}
+	configurePool(db, config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s database %s: %w", name, RedactDSN(dsn), err)
+	}
+	return db, nil
+}
+
+// configurePool applies the connection pool settings of the configuration to a database
+// A small pool suits tests, which run few queries at once, and keeps the suite below the server's connection cap
+func configurePool(db *sql.DB, config *RunnerConfig) {
+	db.SetMaxOpenConns(max(config.MaxOpenConns, 0))
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+}
+
 // LoadFixtures loads fixtures from the specified directory
 func (r *TestRunner) LoadFixtures() error {
 	return r.fixtureManager.LoadFixturesFromDir(r.config.FixturesDir)
 }
 
 // Run runs the tests using the provided testing.M
+// When loading the fixtures or BeforeAll fails the error is logged, the runner cleaned up and 1 returned
+// without running any test, see RunE to handle the error yourself
 func (r *TestRunner) Run(m *testing.M) int {
+	code, err := r.RunE(m)
+	if err != nil {
+		log.Printf("%v", err)
+		r.Cleanup()
+	}
+	return code
+}
+
+// RunE runs the tests like Run but returns setup failures along with exit code 1, leaving Cleanup to the caller
+func (r *TestRunner) RunE(m *testing.M) (int, error) {
 	// Load fixtures
 	if err := r.LoadFixtures(); err != nil {
-		log.Fatalf("Failed to load fixtures: %v", err)
+		return 1, fmt.Errorf("failed to load fixtures: %w", err)
+	}
+
+	// Suite-wide setup that needs the loaded fixtures or the running application
+	if r.config.BeforeAll != nil {
+		if err := r.config.BeforeAll(r); err != nil {
+			return 1, fmt.Errorf("BeforeAll failed, skipping tests: %w", err)
+		}
 	}
 
 	// Run tests
-	return m.Run()
+	code := m.Run()
+
+	if r.config.AfterAll != nil {
+		r.config.AfterAll(r)
+	}
+
+	return code, nil
 }
 
-// WaitForServer checks if the server is ready at the specified URL
-func (r *TestRunner) waitForServer(url string, maxAttempts int) error {
-	for i := range maxAttempts {
-		log.Printf("Waiting for server to be ready at %s (attempt %d/%d)", url, i+1, maxAttempts)
+// waitUntilReady polls the ReadinessFunc, or else the endpoints selected by WaitFor, until they report ready
+func (r *TestRunner) waitUntilReady() error {
+	ctx := context.Background()
+	if r.config.ReadinessFunc != nil {
+		if err := WaitForFunc(ctx, r.config.ReadinessFunc, r.retryPolicy()); err != nil {
+			return fmt.Errorf("application did not become ready in time: %w", err)
+		}
+		return nil
+	}
 
-		// Create a context with timeout for the request
-		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
-		if err != nil {
-			cancel()
-			return fmt.Errorf("failed to create request: %w", err)
+	paths, err := r.config.healthCheckPaths()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err := r.waitForServer(r.URL(path)); err != nil {
+			return fmt.Errorf("server did not start in time: %w", err)
 		}
+	}
+	if r.config.WaitFor == WaitGRPC {
+		if err := WaitForGRPC(ctx, r.grpcConn, r.config.GRPCHealthService, r.retryPolicy()); err != nil {
+			return fmt.Errorf("gRPC server did not start in time: %w", err)
+		}
+	}
+	return nil
+}
 
-		resp, err := r.httpClient.Do(req)
-		cancel() // Always cancel the context to release resources
+// waitForServer checks if the server is ready at the specified URL
+func (r *TestRunner) waitForServer(url string) error {
+	return WaitForHTTP(context.Background(), r.probeClient, url, r.retryPolicy())
+}
 
-		if err == nil && resp.StatusCode == http.StatusOK {
-			resp.Body.Close()
-			log.Printf("Server is ready at %s", url)
-			return nil
-		}
-		if err == nil {
-			resp.Body.Close()
+// retryPolicy returns the readiness policy described by the configuration
+func (r *TestRunner) retryPolicy() RetryPolicy {
+	headers := make(http.Header, len(r.config.HealthCheckHeaders))
+	for name, value := range r.config.HealthCheckHeaders {
+		headers.Set(name, value)
+	}
+	return RetryPolicy{
+		MaxAttempts:       r.config.MaxWaitAttempts,
+		Timeout:           r.config.ReadyTimeout,
+		Interval:          r.config.PollInterval,
+		Multiplier:        r.config.PollMultiplier,
+		MaxInterval:       r.config.MaxPollInterval,
+		AcceptStatusCodes: r.config.HealthCheckStatusCodes,
+		Headers:           headers,
+		Method:            r.config.HealthCheckMethod,
+	}
+}
+
+// IsReady probes each configured health endpoint, or calls the ReadinessFunc, exactly once without retrying
+// A rejected status code is returned as an *UnexpectedStatusError, a gRPC status other than SERVING as a *NotServingError
+func (r *TestRunner) IsReady(ctx context.Context) (bool, error) {
+	if r.config.dataOnly() {
+		return false, errors.New("no application to probe in data-only mode")
+	}
+	if r.config.ReadinessFunc != nil {
+		if err := probeFunc(ctx, r.config.ReadinessFunc); err != nil {
+			return false, err
 		}
-		time.Sleep(1 * time.Second)
+		return true, nil
+	}
+	paths, err := r.config.healthCheckPaths()
+	if err != nil {
+		return false, err
 	}
 
-	return fmt.Errorf("server did not respond after %d attempts", maxAttempts)
+	policy := r.retryPolicy().withDefaults()
+	for _, path := range paths {
+		if err := probeHTTP(ctx, r.probeClient, r.URL(path), policy); err != nil {
+			return false, err
+		}
+	}
+	if r.config.WaitFor == WaitGRPC {
+		if err := probeGRPC(ctx, r.grpcConn, r.config.GRPCHealthService); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
 }
 
 // Cleanup cleans up resources used by the test runner
+// It is safe to call more than once, only the first call has an effect
 func (r *TestRunner) Cleanup() {
-	if r.cleanup != nil {
-		r.cleanup()
+	r.cleanupOnce.Do(func() {
+		if r.stopSignals != nil {
+			r.stopSignals()
+		}
+		if r.cleanup != nil {
+			r.cleanupErr = r.runCleanup()
+		}
+		r.cleanedUp.Store(true)
+	})
+}
+
+// runCleanup runs the cleanup steps, giving up once CleanupTimeout has passed
+// A step still hanging at the deadline is abandoned in its goroutine so the process can exit
+func (r *TestRunner) runCleanup() error {
+	timeout := r.config.CleanupTimeout
+	if timeout <= 0 {
+		return r.cleanup(context.Background(), func(string) {})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	current := "starting cleanup"
+	step := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		current = name
 	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- r.cleanup(ctx, step)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		mu.Lock()
+		defer mu.Unlock()
+		log.Printf("Warning: cleanup did not finish within %s, abandoned while %s", timeout, current)
+		return fmt.Errorf("cleanup timed out after %s while %s", timeout, current)
+	}
+}
+
+// CleanupError returns the error found by the strict cleanup and leak checks or the cleanup timeout, if any
+func (r *TestRunner) CleanupError() error {
+	return r.cleanupErr
 }
 
-// GetHTTPClient returns the HTTP client
+// GetHTTPClient returns the HTTP client, in data-only mode RunnerConfig.HTTPClient or nil
 func (r *TestRunner) GetHTTPClient() *http.Client {
 	return r.httpClient
 }
 
+// URL resolves a path against the base URL and the path prefix
+// Absolute URLs (with a scheme, e.g. "http://other/health") are returned unchanged
+func (r *TestRunner) URL(path string) string {
+	if u, err := url.Parse(path); err == nil && u.IsAbs() {
+		return path
+	}
+	return joinURLPath(r.config.BaseURL, r.config.PathPrefix, path)
+}
+
+// joinURLPath joins URL parts with exactly one slash between non-empty parts
+func joinURLPath(base string, parts ...string) string {
+	result := strings.TrimSuffix(base, "/")
+	for _, part := range parts {
+		part = strings.Trim(part, "/")
+		if part != "" {
+			result += "/" + part
+		}
+	}
+	// Preserve a trailing slash of the last part, some routers distinguish it
+	if n := len(parts); n > 0 && strings.HasSuffix(parts[n-1], "/") && parts[n-1] != "/" {
+		result += "/"
+	}
+	return result
+}
+
 // GetBaseURL returns the base URL for the test server
 func (r *TestRunner) GetBaseURL() string {
 	return r.config.BaseURL
@@ -213,7 +837,50 @@ func (r *TestRunner) GetDB() *sql.DB {
 	return r.db
 }
 
-// GetConfig returns the configuration
+// BeginTx starts a transaction on the primary database that is rolled back when the test finishes
+// Fixtures loaded before the tests are visible inside it, and nothing the test writes through it persists
+// Only code using the returned transaction is isolated, the application under test uses its own connections
+func (r *TestRunner) BeginTx(t testing.TB) *sql.Tx {
+	t.Helper()
+
+	tx, err := r.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to begin test transaction: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("failed to rollback test transaction: %v", err)
+		}
+	})
+	return tx
+}
+
+// GetReadDB returns the read replica connection for assertions, or the primary database without a ReplicaDSN
+func (r *TestRunner) GetReadDB() *sql.DB {
+	if r.readDB != nil {
+		return r.readDB
+	}
+	return r.db
+}
+
+// DB returns the database connection with the given name, or nil if it is not configured
+func (r *TestRunner) DB(name string) *sql.DB {
+	return r.dbs[name]
+}
+
+// FixtureManager returns the fixture manager of the database with the given name, or nil if it is not configured
+func (r *TestRunner) FixtureManager(name string) *FixtureManager {
+	return r.fixtureManagers[name]
+}
+
+// GetDBConnectionString returns the connection string of the primary database
+// With DatabaseNameTemplate set it points at the dedicated database created for the run
+func (r *TestRunner) GetDBConnectionString() string {
+	return r.primaryDSN
+}
+
+// GetConfig returns the effective configuration, a copy of the one passed to NewTestRunner with defaults applied
+// Changing it after NewTestRunner returned has no defined effect
 func (r *TestRunner) GetConfig() *RunnerConfig {
 	return r.config
 }
@@ -0,0 +1,292 @@
+package testkit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newFakeRunner creates a runner on a fake database, cleaned up when the test finishes
+func newFakeRunner(t *testing.T, config *RunnerConfig) (*TestRunner, *fakeDB) {
+	t.Helper()
+
+	fake := newFakeDatabase()
+	t.Cleanup(func() { fakeDatabases.Delete(fake.dsn) })
+	config.DriverName = fakeDriverName
+	config.DBConnectionString = fake.dsn
+
+	runner, err := NewTestRunner(config)
+	if err != nil {
+		t.Fatalf("NewTestRunner() error = %v", err)
+	}
+	t.Cleanup(runner.Cleanup)
+	return runner, fake
+}
+
+func TestRunnerHTTPClient(t *testing.T) {
+	supplied := &http.Client{}
+	tests := []struct {
+		name        string
+		config      *RunnerConfig
+		wantClient  *http.Client
+		wantDefault bool
+	}{
+		{name: "data-only", config: &RunnerConfig{}},
+		{name: "data-only with client", config: &RunnerConfig{HTTPClient: supplied}, wantClient: supplied},
+		{name: "base URL", config: &RunnerConfig{BaseURL: "http://localhost:1"}, wantDefault: true},
+		{
+			name:       "base URL with client",
+			config:     &RunnerConfig{BaseURL: "http://localhost:1", HTTPClient: supplied},
+			wantClient: supplied,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, _ := newFakeRunner(t, tt.config)
+
+			client := runner.GetHTTPClient()
+			switch {
+			case tt.wantDefault:
+				if client == nil || client == supplied {
+					t.Errorf("GetHTTPClient() = %v, want the default client", client)
+				}
+			case client != tt.wantClient:
+				t.Errorf("GetHTTPClient() = %v, want %v", client, tt.wantClient)
+			}
+		})
+	}
+}
+
+func TestNewTestRunnerLeavesConfigUntouched(t *testing.T) {
+	config := &RunnerConfig{BaseURL: "http://localhost:1"}
+	runner, _ := newFakeRunner(t, config)
+	original := *config
+
+	again, err := NewTestRunner(config)
+	if err != nil {
+		t.Fatalf("NewTestRunner() error = %v", err)
+	}
+	t.Cleanup(again.Cleanup)
+
+	if !reflect.DeepEqual(*config, original) {
+		t.Errorf("NewTestRunner() changed the caller's config: %+v, was %+v", *config, original)
+	}
+	if config.MaxWaitAttempts != 0 || config.PollInterval != 0 || config.HealthCheckPath != "" {
+		t.Errorf("defaults were written into the caller's config: %+v", *config)
+	}
+
+	effective := runner.GetConfig()
+	if effective.MaxWaitAttempts != 30 || effective.HealthCheckPath != "/v1/health/liveness" {
+		t.Errorf("GetConfig() = %+v, want the defaults applied", effective)
+	}
+	if effective.BaseURL != config.BaseURL {
+		t.Errorf("GetConfig().BaseURL = %q, want %q", effective.BaseURL, config.BaseURL)
+	}
+}
+
+func TestRunnerURL(t *testing.T) {
+	const local = "http://localhost:8080"
+	tests := []struct {
+		name   string
+		base   string
+		prefix string
+		path   string
+		want   string
+	}{
+		{name: "no prefix", base: local, path: "/v1/users", want: local + "/v1/users"},
+		{name: "prefix", base: local, prefix: "/api", path: "/v1/users", want: local + "/api/v1/users"},
+		{name: "relative path", base: local, prefix: "/api", path: "v1/users", want: local + "/api/v1/users"},
+		{name: "extra slashes", base: local + "/", prefix: "api/", path: "/v1/users", want: local + "/api/v1/users"},
+		{name: "trailing slash kept", base: local, prefix: "/api", path: "/v1/users/", want: local + "/api/v1/users/"},
+		{name: "root path", base: local, prefix: "/api", path: "/", want: local + "/api"},
+		{name: "base with path", base: local + "/svc", prefix: "/api", path: "/health", want: local + "/svc/api/health"},
+		{name: "query kept", base: local, prefix: "/api", path: "/users?limit=1", want: local + "/api/users?limit=1"},
+		{name: "absolute URL", base: local, prefix: "/api", path: "http://other/health", want: "http://other/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &TestRunner{config: &RunnerConfig{BaseURL: tt.base, PathPrefix: tt.prefix}}
+			if got := runner.URL(tt.path); got != tt.want {
+				t.Errorf("URL(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunnerPathPrefixAppliesToHealthChecksAndRequests(t *testing.T) {
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requested = append(requested, req.URL.Path)
+		if !strings.HasPrefix(req.URL.Path, "/api/") {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"ok": true}`)
+	}))
+	t.Cleanup(server.Close)
+
+	runner, _ := newFakeRunner(t, &RunnerConfig{
+		BaseURL:         server.URL,
+		PathPrefix:      "/api",
+		HealthCheckPath: "/health",
+		MaxWaitAttempts: 2,
+		PollInterval:    time.Millisecond,
+	})
+	if err := runner.waitUntilReady(); err != nil {
+		t.Fatalf("waitUntilReady() error = %v", err)
+	}
+
+	var body struct{ OK bool }
+	runner.GetJSON(t, "/v1/users", &body)
+	if !body.OK {
+		t.Errorf("GetJSON() decoded %+v, want the prefixed endpoint's response", body)
+	}
+	if want := []string{"/api/health", "/api/v1/users"}; !reflect.DeepEqual(requested, want) {
+		t.Errorf("requested paths = %q, want %q", requested, want)
+	}
+}
+
+// envSignalHelper makes the test binary act as the interrupted process of TestHandleSignals
+const envSignalHelper = "TESTKIT_SIGNAL_HELPER"
+
+// signalHelper interrupts a runner and reports through the exit code and the marker file of the parent test
+// "interrupt" expects the handler to clean up and exit 1, "after-cleanup" expects a signal after Cleanup to be left
+// to the process
+func signalHelper(t *testing.T, mode, marker string) {
+	var cleanups atomic.Int32
+	runner, _ := newFakeRunner(t, &RunnerConfig{
+		HandleSignals: true,
+		AfterCleanup: func() {
+			cleanups.Add(1)
+			if err := os.WriteFile(marker, []byte("cleaned up"), 0o600); err != nil {
+				t.Errorf("failed to write marker: %v", err)
+			}
+		},
+	})
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatalf("failed to find own process: %v", err)
+	}
+	switch mode {
+	case "interrupt":
+		if err := process.Signal(syscall.SIGTERM); err != nil {
+			t.Fatalf("failed to send SIGTERM: %v", err)
+		}
+		time.Sleep(5 * time.Second)
+		// Exit without the cleanup and with a code of its own, a failed test would also exit 1
+		fmt.Println("the process was not exited by the signal handler")
+		os.Exit(3)
+	case "after-cleanup":
+		runner.Cleanup()
+		received := make(chan os.Signal, 1)
+		signal.Notify(received, syscall.SIGTERM)
+		defer signal.Stop(received)
+		if err := process.Signal(syscall.SIGTERM); err != nil {
+			t.Fatalf("failed to send SIGTERM: %v", err)
+		}
+		<-received
+		// Give a handler that is still installed the time to run
+		time.Sleep(100 * time.Millisecond)
+		if n := cleanups.Load(); n != 1 {
+			t.Fatalf("cleanups = %d, want only the normal one", n)
+		}
+	}
+}
+
+func TestHandleSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test: signals cannot be sent to a process on Windows")
+	}
+	if mode, marker, ok := strings.Cut(os.Getenv(envSignalHelper), ":"); ok {
+		signalHelper(t, mode, marker)
+		return
+	}
+
+	tests := []struct {
+		mode     string
+		wantCode int
+	}{
+		{mode: "interrupt", wantCode: 1},
+		{mode: "after-cleanup", wantCode: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			marker := filepath.Join(t.TempDir(), "cleaned")
+			cmd := exec.Command(os.Args[0], "-test.run=^TestHandleSignals$")
+			cmd.Env = append(os.Environ(), envSignalHelper+"="+tt.mode+":"+marker)
+			output, err := cmd.CombinedOutput()
+
+			code := 0
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				code = exitErr.ExitCode()
+			} else if err != nil {
+				t.Fatalf("failed to run the interrupted process: %v", err)
+			}
+			if code != tt.wantCode {
+				t.Fatalf("exit code = %d, want %d, output:\n%s", code, tt.wantCode, output)
+			}
+			if _, err := os.Stat(marker); err != nil {
+				t.Errorf("cleanup did not run before the process exited: %v", err)
+			}
+		})
+	}
+}
+
+func TestDataOnlyRunner(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureIn(t, dir, "users.yml", "users:\n  - name: alice\n")
+	runner, fake := newFakeRunner(t, &RunnerConfig{FixturesDir: dir})
+	fake.returnIDs()
+
+	if err := runner.LoadFixtures(); err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	if inserts := fake.queryTexts(`INSERT INTO "users"`); len(inserts) != 1 {
+		t.Errorf("inserts = %q, want the fixtures seeded without an application", inserts)
+	}
+	if client := runner.GetHTTPClient(); client != nil {
+		t.Errorf("GetHTTPClient() = %v, want no HTTP client", client)
+	}
+	if ready, err := runner.IsReady(t.Context()); ready || err == nil || !strings.Contains(err.Error(), "data-only") {
+		t.Errorf("IsReady() = %v, %v, want an error for the data-only mode", ready, err)
+	}
+	description := runner.Describe()
+	if !strings.Contains(description, "data-only") || strings.Contains(description, "health URL") {
+		t.Errorf("Describe() = %q, want the data-only mode without a health URL", description)
+	}
+}
+
+func TestRunnerConfigDataOnly(t *testing.T) {
+	tests := []struct {
+		name   string
+		config RunnerConfig
+		want   bool
+	}{
+		{name: "nothing to probe", config: RunnerConfig{FixturesDir: "fixtures"}, want: true},
+		{name: "health check path alone", config: RunnerConfig{HealthCheckPath: "/health"}, want: true},
+		{name: "base URL", config: RunnerConfig{BaseURL: "http://localhost:8080"}},
+		{name: "gRPC address", config: RunnerConfig{GRPCAddress: "localhost:9090"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.dataOnly(); got != tt.want {
+				t.Errorf("dataOnly() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
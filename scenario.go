@@ -0,0 +1,143 @@
+package testkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+// Scenario is a sequence of seed, call and assert steps run against a TestRunner
+// Steps are recorded by the builder methods and executed in order by Run
+type Scenario struct {
+	runner *TestRunner
+	steps  []scenarioStep
+	// Response of the most recent Call, read by Expect
+	status int
+	body   []byte
+}
+
+// scenarioStep is a named step of a scenario
+type scenarioStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// NewScenario starts an empty scenario for the runner
+func NewScenario(runner *TestRunner) *Scenario {
+	return &Scenario{runner: runner}
+}
+
+// Step appends a custom step
+func (s *Scenario) Step(name string, fn func(ctx context.Context) error) *Scenario {
+	s.steps = append(s.steps, scenarioStep{name: name, run: fn})
+	return s
+}
+
+// Seed loads a fixture file into the primary database
+// Relative paths are resolved against the runner's FixturesDir
+func (s *Scenario) Seed(fixturePath string) *Scenario {
+	if !filepath.IsAbs(fixturePath) && s.runner.config.FixturesDir != "" {
+		fixturePath = filepath.Join(s.runner.config.FixturesDir, fixturePath)
+	}
+	return s.Step("seed "+fixturePath, func(context.Context) error {
+		return s.runner.fixtureManager.LoadFixtureFile(fixturePath)
+	})
+}
+
+// Call sends a request to the path with the runner's HTTP client
+// A non-nil body is sent as is when it is []byte or string, otherwise it is encoded as JSON
+func (s *Scenario) Call(method, path string, body any) *Scenario {
+	return s.Step(fmt.Sprintf("call %s %s", method, path), func(ctx context.Context) error {
+		if s.runner.httpClient == nil {
+			return fmt.Errorf("the runner has no HTTP client in data-only mode")
+		}
+		reader, contentType, err := requestBody(body)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, s.runner.URL(path), reader)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := s.runner.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		s.status = resp.StatusCode
+		s.body = respBody
+		return nil
+	})
+}
+
+// Expect checks the status code of the previous Call
+func (s *Scenario) Expect(statusCode int) *Scenario {
+	return s.Step(fmt.Sprintf("expect status %d", statusCode), func(context.Context) error {
+		if s.status == 0 {
+			return fmt.Errorf("no request was made before the expectation")
+		}
+		if s.status != statusCode {
+			return fmt.Errorf("got status %d, body: %s", s.status, s.body)
+		}
+		return nil
+	})
+}
+
+// AssertRow checks that the table of the primary database has a row matching where
+func (s *Scenario) AssertRow(table string, where map[string]any) *Scenario {
+	return s.Step("assert row in "+table, func(ctx context.Context) error {
+		count, err := s.runner.fixtureManager.CountRows(ctx, table, where)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return fmt.Errorf("no row in table %s matches %v", table, where)
+		}
+		return nil
+	})
+}
+
+// Run executes the steps in order and fails the test at the first failing step
+func (s *Scenario) Run(t testing.TB) {
+	t.Helper()
+
+	ctx := context.Background()
+	for i, step := range s.steps {
+		if err := step.run(ctx); err != nil {
+			t.Fatalf("scenario step %d (%s) failed: %v", i+1, step.name, err)
+		}
+	}
+}
+
+// requestBody encodes a request body and returns its content type
+func requestBody(body any) (io.Reader, string, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, "", nil
+	case []byte:
+		return bytes.NewReader(b), "", nil
+	case string:
+		return bytes.NewReader([]byte(b)), "", nil
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode request body: %w", err)
+		}
+		return bytes.NewReader(data), "application/json", nil
+	}
+}
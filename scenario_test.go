@@ -0,0 +1,131 @@
+package testkit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// scenarioServer answers POST /v1/orders with 201 and records the decoded JSON bodies, other requests get 404
+func scenarioServer(t *testing.T) (*httptest.Server, *[]map[string]any) {
+	t.Helper()
+
+	var orders []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost || req.URL.Path != "/v1/orders" {
+			http.NotFound(w, req)
+			return
+		}
+		var order map[string]any
+		if req.Header.Get("Content-Type") != "application/json" || json.NewDecoder(req.Body).Decode(&order) != nil {
+			http.Error(w, "want a JSON body", http.StatusBadRequest)
+			return
+		}
+		orders = append(orders, order)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(server.Close)
+	return server, &orders
+}
+
+func TestScenario(t *testing.T) {
+	server, orders := scenarioServer(t)
+	dir := t.TempDir()
+	writeFixtureIn(t, dir, "users.yml", "users:\n  - id: 1\n    name: alice\n")
+	runner, fake := newFakeRunner(t, &RunnerConfig{BaseURL: server.URL, FixturesDir: dir})
+	fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+		switch {
+		case isColumnQuery(query):
+			return columnRows(map[string]string{"id": "integer", "name": "text"}), nil
+		case strings.HasPrefix(query, "SELECT COUNT(*)"):
+			return &fakeRows{columns: []string{"count"}, rows: [][]any{{int64(1)}}}, nil
+		default:
+			return &fakeRows{columns: []string{"id"}, rows: [][]any{{int64(1)}}}, nil
+		}
+	}
+
+	NewScenario(runner).
+		Seed("users.yml").
+		Call(http.MethodPost, "/v1/orders", map[string]any{"user_id": 1}).
+		Expect(http.StatusCreated).
+		AssertRow("orders", map[string]any{"user_id": 1}).
+		Run(t)
+
+	if inserts := fake.queryTexts(`INSERT INTO "users"`); len(inserts) != 1 {
+		t.Errorf("inserts = %q, want the seeded user", inserts)
+	}
+	if len(*orders) != 1 || (*orders)[0]["user_id"] != float64(1) {
+		t.Errorf("server received %v, want the JSON encoded order", *orders)
+	}
+	counts := fake.queries("SELECT COUNT(*)")
+	if len(counts) != 1 || !strings.Contains(counts[0].Query, `FROM "orders"`) || counts[0].Args[0] != 1 {
+		t.Errorf("count queries = %v, want one for the order of user 1", counts)
+	}
+}
+
+func TestScenarioFailingStep(t *testing.T) {
+	server, _ := scenarioServer(t)
+	tests := []struct {
+		name     string
+		dataOnly bool
+		build    func(s *Scenario) *Scenario
+		want     string
+	}{
+		{
+			name:  "expect without call",
+			build: func(s *Scenario) *Scenario { return s.Expect(http.StatusOK) },
+			want:  "scenario step 1 (expect status 200) failed: no request was made before the expectation",
+		},
+		{
+			name: "status mismatch",
+			build: func(s *Scenario) *Scenario {
+				return s.Call(http.MethodGet, "/v1/missing", nil).Expect(http.StatusOK)
+			},
+			want: "scenario step 2 (expect status 200) failed: got status 404",
+		},
+		{
+			name: "missing row",
+			build: func(s *Scenario) *Scenario {
+				return s.AssertRow("orders", map[string]any{"user_id": 2})
+			},
+			want: "scenario step 1 (assert row in orders) failed: no row in table orders matches map[user_id:2]",
+		},
+		{
+			name:     "data-only runner",
+			dataOnly: true,
+			build:    func(s *Scenario) *Scenario { return s.Call(http.MethodGet, "/", nil) },
+			want:     "scenario step 1 (call GET /) failed: the runner has no HTTP client in data-only mode",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &RunnerConfig{BaseURL: server.URL}
+			if tt.dataOnly {
+				config = &RunnerConfig{}
+			}
+			runner, fake := newFakeRunner(t, config)
+			fake.onQuery = func(string, []any) (*fakeRows, error) {
+				return &fakeRows{columns: []string{"count"}, rows: [][]any{{int64(0)}}}, nil
+			}
+
+			// A later step must not run once a step failed
+			var ran bool
+			scenario := tt.build(NewScenario(runner)).Step("after", func(context.Context) error {
+				ran = true
+				return nil
+			})
+			recorder := &recordingTB{TB: t}
+			recorder.run(scenario.Run)
+
+			if len(recorder.errors) != 1 || !strings.HasPrefix(recorder.errors[0], tt.want) {
+				t.Errorf("Run() failures = %q, want one starting with %q", recorder.errors, tt.want)
+			}
+			if ran {
+				t.Error("Run() ran the step after the failing one")
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// columnType describes a column as reported by information_schema.columns
+type columnType struct {
+	DataType  string
+	UDTSchema string
+	UDTName   string
+}
+
+// cast returns the SQL type a bound value should be cast to, or an empty string when no cast is needed
+func (ct columnType) cast() string {
+	switch ct.DataType {
+	case "uuid", "json", "jsonb":
+		return ct.DataType
+	case "USER-DEFINED":
+		// Enum and domain types are referenced by their schema-qualified name
+		return fmt.Sprintf("%q.%q", ct.UDTSchema, ct.UDTName)
+	default:
+		return ""
+	}
+}
+
+// isTemporal reports whether the column stores timestamps or dates
+func (ct columnType) isTemporal() bool {
+	return strings.HasPrefix(ct.DataType, "timestamp") || ct.DataType == "date"
+}
+
+// isJSON reports whether the column stores JSON documents
+func (ct columnType) isJSON() bool {
+	return ct.DataType == "json" || ct.DataType == "jsonb"
+}
+
+// splitTableName splits "schema.table" into the schema, nil for unqualified names, and the table name
+// The catalog queries resolve a nil schema to the current schema
+func splitTableName(tableName string) (schema any, name string) {
+	if i := strings.LastIndex(tableName, "."); i >= 0 {
+		return tableName[:i], tableName[i+1:]
+	}
+	return nil, tableName
+}
+
+// getColumnTypes returns the column types of a table, querying the catalog only once per table
+func (fm *FixtureManager) getColumnTypes(ctx context.Context, q querier, tableName string) (map[string]columnType, error) {
+	fm.mu.Lock()
+	types, ok := fm.columnTypes[tableName]
+	fm.mu.Unlock()
+	if ok {
+		return types, nil
+	}
+
+	schema, name := splitTableName(tableName)
+	rows, err := q.QueryContext(ctx, `
+		SELECT column_name, data_type, udt_schema, udt_name
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE($1::text, current_schema()) AND table_name = $2`,
+		schema, name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query column types for table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	types = make(map[string]columnType)
+	for rows.Next() {
+		var column string
+		var ct columnType
+		if err := rows.Scan(&column, &ct.DataType, &ct.UDTSchema, &ct.UDTName); err != nil {
+			return nil, fmt.Errorf("failed to scan column type for table %s: %w", tableName, err)
+		}
+		types[column] = ct
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read column types for table %s: %w", tableName, err)
+	}
+
+	fm.mu.Lock()
+	fm.columnTypes[tableName] = types
+	fm.mu.Unlock()
+	return types, nil
+}
+
+// nullValue returns the typed NULL bound for the column, so drivers never have to guess the type of a nil
+func (ct columnType) nullValue() any {
+	switch {
+	case ct.isTemporal():
+		return sql.NullTime{}
+	case ct.DataType == "boolean":
+		return sql.NullBool{}
+	case ct.DataType == "smallint" || ct.DataType == "integer" || ct.DataType == "bigint":
+		return sql.NullInt64{}
+	case ct.DataType == "real" || ct.DataType == "double precision":
+		return sql.NullFloat64{}
+	default:
+		return sql.NullString{}
+	}
+}
+
+// bindTyped adapts a value to its column type and returns the placeholder cast to use
+func bindTyped(ct columnType, value any) (any, string, error) {
+	switch {
+	case value == nil:
+		value = ct.nullValue()
+	case ct.isTemporal():
+		// Quoted timestamps arrive as strings, bind them as time.Time like unquoted ones
+		if v, ok := value.(string); ok {
+			if parsed, err := parseTimestamp(v); err == nil {
+				value = parsed
+			}
+		}
+	case ct.DataType == "interval":
+		switch v := value.(type) {
+		case string:
+			if d, err := time.ParseDuration(v); err == nil {
+				value = intervalLiteral(d)
+			}
+		case time.Duration:
+			value = intervalLiteral(v)
+		}
+	case ct.DataType == "ARRAY":
+		// YAML lists decode as []any, which database/sql cannot bind, encode them as an array literal
+		if v, ok := value.([]any); ok {
+			value = pq.Array(v)
+		}
+	case ct.isJSON():
+		switch value.(type) {
+		case map[string]any, []any:
+			var err error
+			if value, err = encodeJSON(value); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	return value, ct.cast(), nil
+}
+
+// bindStructured encodes the maps and lists of a column whose type is not introspected
+// The hint set with ConfigureColumnTypes picks JSON ("json" or "jsonb") or pq.Array ("array"); without one maps
+// and lists holding maps or lists are encoded as JSON, other lists as arrays on PostgreSQL and as JSON elsewhere
+func (fm *FixtureManager) bindStructured(hint string, value any) (any, error) {
+	switch value.(type) {
+	case map[string]any, []any:
+	default:
+		return value, nil
+	}
+
+	switch hint {
+	case "json", "jsonb":
+		return encodeJSON(value)
+	case "array":
+		list, ok := value.([]any)
+		if !ok {
+			return nil, fmt.Errorf("array column holds a map")
+		}
+		return pq.Array(list), nil
+	case "":
+		if list, ok := value.([]any); ok && fm.config.Dialect == DialectPostgres && !hasStructuredItems(list) {
+			return pq.Array(list), nil
+		}
+		return encodeJSON(value)
+	default:
+		return nil, fmt.Errorf("unknown column type hint %q, expected json, jsonb or array", hint)
+	}
+}
+
+// hasStructuredItems reports whether a list holds maps or lists, which only JSON can encode
+func hasStructuredItems(list []any) bool {
+	for _, item := range list {
+		switch item.(type) {
+		case map[string]any, []any:
+			return true
+		}
+	}
+	return false
+}
+
+// encodeJSON encodes a fixture value as a JSON string
+func encodeJSON(value any) (string, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JSON value: %w", err)
+	}
+	return string(encoded), nil
+}
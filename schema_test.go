@@ -0,0 +1,410 @@
+package testkit
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// typedManager returns a manager with TypeAwareBinding on a fake database describing the given columns
+func typedManager(t *testing.T, types map[string]string) (*FixtureManager, *fakeDB) {
+	t.Helper()
+
+	config := DefaultFixtureConfig()
+	config.TypeAwareBinding = true
+	fm, fake := newFakeManager(t, config)
+	fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+		if isColumnQuery(query) {
+			return columnRows(types), nil
+		}
+		return &fakeRows{columns: []string{"id"}, rows: [][]any{{int64(1)}}}, nil
+	}
+	return fm, fake
+}
+
+func TestTypeAwareBindingCasts(t *testing.T) {
+	fm, fake := typedManager(t, map[string]string{
+		"id":       "integer",
+		"token":    "uuid",
+		"settings": "jsonb",
+		"mood":     "USER-DEFINED:mood",
+		"name":     "text",
+	})
+
+	fixture := writeFixture(t, "users.yml", `
+users:
+  - token: 0b9c4c8e-3c55-4b8c-9d4f-1c8b7a6e5d4c
+    settings: {theme: dark, tags: [a, b]}
+    mood: happy
+    name: alice
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queries("INSERT")
+	want := `INSERT INTO "users" ("mood", "name", "settings", "token") ` +
+		`VALUES ($1::"public"."mood", $2, $3::jsonb, $4::uuid) RETURNING "id"`
+	if len(inserts) != 1 || inserts[0].Query != want {
+		t.Fatalf("inserts = %v, want %q", inserts, want)
+	}
+	if settings := inserts[0].Args[2]; settings != `{"tags":["a","b"],"theme":"dark"}` {
+		t.Errorf("settings = %v, want the map encoded as JSON", settings)
+	}
+	if n := countColumnQueries(fake); n != 1 {
+		t.Errorf("catalog queries = %d, want 1", n)
+	}
+
+	// The column types are cached for later loads of the table
+	fake.reset()
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("second LoadYAMLFixtures() error = %v", err)
+	}
+	if n := countColumnQueries(fake); n != 0 {
+		t.Errorf("catalog queries = %d on the second load, want the cached types", n)
+	}
+}
+
+// countColumnQueries returns the number of logged information_schema.columns queries
+func countColumnQueries(fake *fakeDB) int {
+	var n int
+	for _, statement := range fake.queryTexts("") {
+		if isColumnQuery(statement) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestColumnTypeCast(t *testing.T) {
+	tests := []struct {
+		ct   columnType
+		want string
+	}{
+		{ct: columnType{DataType: "uuid"}, want: "uuid"},
+		{ct: columnType{DataType: "jsonb"}, want: "jsonb"},
+		{ct: columnType{DataType: "json"}, want: "json"},
+		{ct: columnType{DataType: "USER-DEFINED", UDTSchema: "billing", UDTName: "Status"}, want: `"billing"."Status"`},
+		{ct: columnType{DataType: "text"}, want: ""},
+		{ct: columnType{DataType: "integer"}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := tt.ct.cast(); got != tt.want {
+			t.Errorf("%+v cast() = %q, want %q", tt.ct, got, tt.want)
+		}
+	}
+}
+
+func TestTypeAwareBindingPostgres(t *testing.T) {
+	db := newPostgresDB(t,
+		`CREATE TYPE mood AS ENUM ('happy', 'sad')`,
+		`CREATE TABLE users (id serial PRIMARY KEY, token uuid NOT NULL, settings jsonb NOT NULL, mood mood NOT NULL)`,
+	)
+	config := DefaultFixtureConfig()
+	config.TypeAwareBinding = true
+	fm := NewFixtureManagerWithConfig(db, config)
+
+	fixture := writeFixture(t, "users.yml", `
+users:
+  - token: 0b9c4c8e-3c55-4b8c-9d4f-1c8b7a6e5d4c
+    settings: {theme: dark}
+    mood: happy
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	var theme, mood string
+	if err := db.QueryRow("SELECT settings->>'theme', mood::text FROM users").Scan(&theme, &mood); err != nil {
+		t.Fatalf("failed to read user: %v", err)
+	}
+	if theme != "dark" || mood != "happy" {
+		t.Errorf("user = %s/%s, want dark/happy", theme, mood)
+	}
+}
+
+func TestTypeAwareBindingTimestampsAndIntervals(t *testing.T) {
+	fm, fake := typedManager(t, map[string]string{
+		"id":         "integer",
+		"expires_at": "timestamp with time zone",
+		"starts_on":  "date",
+		"ttl":        "interval",
+	})
+
+	fixture := writeFixture(t, "sessions.yml", `
+sessions:
+  - expires_at: 2023-01-01T00:00:00Z
+    starts_on: 2023-01-01
+    ttl: 24h
+  - expires_at: "2023-01-01T00:00:00Z"
+    starts_on: "2023-01-01"
+    ttl: "24h"
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queries("INSERT")
+	if len(inserts) != 2 {
+		t.Fatalf("inserts = %v, want one per row", inserts)
+	}
+	wantTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, insert := range inserts {
+		// Columns are bound in sorted order: expires_at, starts_on, ttl
+		for _, arg := range insert.Args[:2] {
+			if got, ok := arg.(time.Time); !ok || !got.Equal(wantTime) {
+				t.Errorf("row %d timestamp = %#v, want time.Time %v regardless of quoting", i, arg, wantTime)
+			}
+		}
+		if ttl := insert.Args[2]; ttl != "86400000000 microseconds" {
+			t.Errorf("row %d ttl = %#v, want the duration as an interval", i, ttl)
+		}
+	}
+}
+
+func TestTimestampAndDurationTags(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+
+	fixture := writeFixture(t, "sessions.yml", `
+sessions:
+  - expires_at: !timestamp "2023-01-01 12:00:00"
+    ttl: !duration 90m
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queries("INSERT")
+	if len(inserts) != 1 {
+		t.Fatalf("inserts = %v, want 1", inserts)
+	}
+	want := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	if got, ok := inserts[0].Args[0].(time.Time); !ok || !got.Equal(want) {
+		t.Errorf("expires_at = %#v, want time.Time %v without type-aware binding", inserts[0].Args[0], want)
+	}
+	if ttl := inserts[0].Args[1]; ttl != "5400000000 microseconds" {
+		t.Errorf("ttl = %#v, want the duration as an interval", ttl)
+	}
+
+	bad := writeFixture(t, "bad.yml", "sessions:\n  - ttl: !duration soon\n")
+	if err := fm.LoadYAMLFixtures(bad); err == nil {
+		t.Error("LoadYAMLFixtures() error = nil, want the invalid duration reported")
+	}
+}
+
+// arrayLiteral returns the PostgreSQL array literal a bound value encodes to, failing unless it is a driver.Valuer
+func arrayLiteral(t *testing.T, value any) any {
+	t.Helper()
+
+	valuer, ok := value.(driver.Valuer)
+	if !ok {
+		t.Fatalf("bound value %#v is not wrapped with pq.Array", value)
+	}
+	literal, err := valuer.Value()
+	if err != nil {
+		t.Fatalf("failed to encode array %#v: %v", value, err)
+	}
+	return literal
+}
+
+func TestTypeAwareBindingArrays(t *testing.T) {
+	fm, fake := typedManager(t, map[string]string{
+		"id":     "integer",
+		"scores": "ARRAY:_int4",
+		"tags":   "ARRAY:_text",
+	})
+
+	fixture := writeFixture(t, "players.yml", "players:\n  - scores: [1, 2, 3]\n    tags: [a, b c]\n")
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queries("INSERT")
+	want := `INSERT INTO "players" ("scores", "tags") VALUES ($1, $2) RETURNING "id"`
+	if len(inserts) != 1 || inserts[0].Query != want {
+		t.Fatalf("inserts = %v, want %q", inserts, want)
+	}
+	if got := arrayLiteral(t, inserts[0].Args[0]); got != "{1,2,3}" {
+		t.Errorf("scores = %v, want {1,2,3}", got)
+	}
+	if got := arrayLiteral(t, inserts[0].Args[1]); got != `{"a","b c"}` {
+		t.Errorf("tags = %v, want {\"a\",\"b c\"}", got)
+	}
+}
+
+func TestColumnTypeHintArrays(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		hint    string
+		wantErr string
+		want    any
+	}{
+		{name: "hint", dialect: DialectPostgres, hint: "array", want: `{"a","b"}`},
+		{name: "hint overrides dialect", dialect: DialectSQLite, hint: "array", want: `{"a","b"}`},
+		{name: "no hint on postgres", dialect: DialectPostgres, want: `{"a","b"}`},
+		{name: "no hint elsewhere", dialect: DialectSQLite, want: `["a","b"]`},
+		{name: "json hint", dialect: DialectPostgres, hint: "jsonb", want: `["a","b"]`},
+		{name: "unknown hint", dialect: DialectPostgres, hint: "list", wantErr: `unknown column type hint "list"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.Dialect = tt.dialect
+			fm, _ := newFakeManager(t, config)
+
+			got, err := fm.bindStructured(tt.hint, []any{"a", "b"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("bindStructured() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bindStructured() error = %v", err)
+			}
+			if _, isString := got.(string); !isString {
+				got = arrayLiteral(t, got)
+			}
+			if got != tt.want {
+				t.Errorf("bindStructured() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	fm, _ := newFakeManager(t, nil)
+	if _, err := fm.bindStructured("array", map[string]any{"a": 1}); err == nil {
+		t.Error("bindStructured(array, map) error = nil, want the map rejected")
+	}
+}
+
+func TestArrayColumnsPostgres(t *testing.T) {
+	db := newPostgresDB(t, "CREATE TABLE players (id serial PRIMARY KEY, scores int[] NOT NULL, tags text[] NOT NULL)")
+	for _, typeAware := range []bool{false, true} {
+		config := DefaultFixtureConfig()
+		config.TypeAwareBinding = typeAware
+		fm := NewFixtureManagerWithConfig(db, config)
+
+		fixture := writeFixture(t, "players.yml", "players:\n  - scores: [1, 2, 3]\n    tags: [a, b c]\n")
+		if err := fm.LoadYAMLFixtures(fixture); err != nil {
+			t.Fatalf("LoadYAMLFixtures() with TypeAwareBinding=%v error = %v", typeAware, err)
+		}
+		var matches bool
+		err := db.QueryRow("SELECT scores = ARRAY[1, 2, 3] AND tags = ARRAY['a', 'b c'] FROM players").Scan(&matches)
+		if err != nil {
+			t.Fatalf("failed to read players: %v", err)
+		}
+		if !matches {
+			t.Errorf("arrays loaded with TypeAwareBinding=%v differ from the fixture", typeAware)
+		}
+		if err := fm.CleanupFixtures(); err != nil {
+			t.Fatalf("CleanupFixtures() error = %v", err)
+		}
+	}
+}
+
+func TestColumnTypeNullValue(t *testing.T) {
+	tests := map[string]any{
+		"timestamp with time zone": sql.NullTime{},
+		"date":                     sql.NullTime{},
+		"boolean":                  sql.NullBool{},
+		"integer":                  sql.NullInt64{},
+		"bigint":                   sql.NullInt64{},
+		"double precision":         sql.NullFloat64{},
+		"text":                     sql.NullString{},
+		"uuid":                     sql.NullString{},
+	}
+	for dataType, want := range tests {
+		if got := (columnType{DataType: dataType}).nullValue(); got != want {
+			t.Errorf("nullValue() of %s = %#v, want %#v", dataType, got, want)
+		}
+	}
+}
+
+func TestExplicitNullsAndOmittedColumns(t *testing.T) {
+	const fixture = `
+users:
+  - name: alice
+    deleted_at: null
+    age: ~
+`
+	tests := []struct {
+		name      string
+		typeAware bool
+		want      []any
+	}{
+		{name: "untyped", want: []any{nil, nil, "alice"}},
+		{name: "type-aware", typeAware: true, want: []any{sql.NullInt64{}, sql.NullTime{}, "alice"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.TypeAwareBinding = tt.typeAware
+			fm, fake := newFakeManager(t, config)
+			fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+				if isColumnQuery(query) {
+					return columnRows(map[string]string{
+						"id":         "integer",
+						"name":       "text",
+						"age":        "integer",
+						"deleted_at": "timestamp with time zone",
+						"status":     "text",
+					}), nil
+				}
+				return &fakeRows{columns: []string{"id"}, rows: [][]any{{int64(1)}}}, nil
+			}
+
+			if err := fm.LoadYAMLFixtures(writeFixture(t, "users.yml", fixture)); err != nil {
+				t.Fatalf("LoadYAMLFixtures() error = %v", err)
+			}
+			inserts := fake.queries("INSERT")
+			// The omitted status column is left to its default
+			want := `INSERT INTO "users" ("age", "deleted_at", "name") VALUES ($1, $2, $3) RETURNING "id"`
+			if len(inserts) != 1 || inserts[0].Query != want {
+				t.Fatalf("inserts = %v, want %q", inserts, want)
+			}
+			if !reflect.DeepEqual(inserts[0].Args, tt.want) {
+				t.Errorf("args = %#v, want %#v", inserts[0].Args, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplicitNullsAndDefaultsPostgres(t *testing.T) {
+	db := newPostgresDB(t, `CREATE TABLE users (
+		id serial PRIMARY KEY,
+		name text NOT NULL,
+		status text DEFAULT 'active',
+		deleted_at timestamptz DEFAULT now(),
+		age int DEFAULT 18
+	)`)
+	for _, typeAware := range []bool{false, true} {
+		config := DefaultFixtureConfig()
+		config.TypeAwareBinding = typeAware
+		fm := NewFixtureManagerWithConfig(db, config)
+
+		fixture := writeFixture(t, "users.yml", "users:\n  - name: alice\n    deleted_at: null\n    age: null\n")
+		if err := fm.LoadYAMLFixtures(fixture); err != nil {
+			t.Fatalf("LoadYAMLFixtures() with TypeAwareBinding=%v error = %v", typeAware, err)
+		}
+		var status sql.NullString
+		var deletedAt sql.NullTime
+		var age sql.NullInt64
+		if err := db.QueryRow("SELECT status, deleted_at, age FROM users").Scan(&status, &deletedAt, &age); err != nil {
+			t.Fatalf("failed to read users: %v", err)
+		}
+		if status.String != "active" {
+			t.Errorf("status = %v with TypeAwareBinding=%v, want the default of the omitted column", status, typeAware)
+		}
+		if deletedAt.Valid || age.Valid {
+			t.Errorf("deleted_at, age = %v, %v with TypeAwareBinding=%v, want NULL stored", deletedAt, age, typeAware)
+		}
+		if err := fm.CleanupFixtures(); err != nil {
+			t.Fatalf("CleanupFixtures() error = %v", err)
+		}
+	}
+}
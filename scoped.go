@@ -0,0 +1,132 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// LoadFixtureFileWithCleanup loads a fixture file like LoadFixtureFile and returns a function removing exactly its rows
+// Rows loaded by other calls stay in place, and removed rows are no longer tracked for CleanupFixtures
+// The cleanup function is returned even when the load fails, so rows of transactions committed before the failure
+// can be removed too
+func (fm *FixtureManager) LoadFixtureFileWithCleanup(fixturePath string) (func() error, error) {
+	return fm.loadFileWithCleanup(context.Background(), fixturePath)
+}
+
+// loadFileWithCleanup implements LoadFixtureFileWithCleanup, ctx only bounds the load and not the cleanup
+func (fm *FixtureManager) loadFileWithCleanup(ctx context.Context, fixturePath string) (func() error, error) {
+	load := &loadState{aliases: newAliasRegistry(), committed: make(map[string][]trackedRecord)}
+	cleanup := func() error {
+		return fm.removeRecords(context.Background(), load.committed)
+	}
+
+	fsys, name := osFile(fixturePath)
+	fixtures, order, err := fm.readFixtures(fsys, name, fm.parserFor(path.Ext(name)))
+	if err != nil {
+		return cleanup, err
+	}
+	load.dependencies = orderDependencies(order)
+	return cleanup, fm.runLoad(ctx, sourceName(fsys, name), fixtures, load)
+}
+
+// removeRecords deletes the given tracked rows in one transaction and stops tracking them
+func (fm *FixtureManager) removeRecords(ctx context.Context, records map[string][]trackedRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+
+	tx, err := fm.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin cleanup transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Printf("failed to rollback cleanup transaction: %v", err)
+		}
+	}()
+
+	tables := make([]string, 0, len(records))
+	for tableName := range records {
+		tables = append(tables, tableName)
+	}
+	sort.Strings(tables)
+	if tables, err = fm.cleanupOrder(ctx, tx, tables); err != nil {
+		return err
+	}
+	for _, tableName := range tables {
+		if err := fm.deleteRecords(ctx, tx, tableName, records[tableName]); err != nil {
+			return err
+		}
+	}
+
+	if err := fm.commit(tx); err != nil {
+		return fmt.Errorf("failed to commit cleanup transaction: %w", err)
+	}
+
+	for tableName, removed := range records {
+		fm.untrack(tableName, removed)
+	}
+	return nil
+}
+
+// untrack drops removed rows from the table's tracked records
+// The caller must hold fm.mu
+func (fm *FixtureManager) untrack(tableName string, removed []trackedRecord) {
+	tracked, ok := fm.insertedRecords[tableName]
+	if !ok {
+		return
+	}
+
+	// Rows are identified by their origin and keys, as the same file may be loaded more than once
+	ids := make(map[string]struct{}, len(removed))
+	for _, record := range removed {
+		ids[record.id()] = struct{}{}
+	}
+	kept := tracked[:0]
+	for _, record := range tracked {
+		if _, ok := ids[record.id()]; !ok {
+			kept = append(kept, record)
+		}
+	}
+	if len(kept) == 0 {
+		delete(fm.insertedRecords, tableName)
+		return
+	}
+	fm.insertedRecords[tableName] = kept
+}
+
+// id identifies a tracked row, fmt prints map keys in sorted order
+func (r trackedRecord) id() string {
+	return fmt.Sprintf("%s\x00%d\x00%v", r.Source, r.Index, r.Keys)
+}
+
+// LoadFixtureFile loads a fixture file into the primary database for a single test
+// Relative paths are resolved against the runner's FixturesDir, and when the test finishes only the rows of this
+// file are removed, leaving the data loaded before the tests untouched
+func (r *TestRunner) LoadFixtureFile(t testing.TB, fixturePath string) {
+	t.Helper()
+
+	if !filepath.IsAbs(fixturePath) && r.config.FixturesDir != "" {
+		fixturePath = filepath.Join(r.config.FixturesDir, fixturePath)
+	}
+	// The test context is cancelled before cleanups run, so it only bounds the load
+	cleanup, err := r.fixtureManager.loadFileWithCleanup(t.Context(), fixturePath)
+	t.Cleanup(func() {
+		if err := cleanup(); err != nil {
+			t.Errorf("failed to remove fixtures of %s: %v", fixturePath, err)
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to load fixtures from %s: %v", fixturePath, err)
+	}
+}
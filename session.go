@@ -0,0 +1,97 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// FixtureSession runs fixture loading, queries and assertions on a single transaction
+// Rolling the session back undoes everything it did, which isolates repository-level tests
+// The code under test only sees the seeded rows if it uses the same transaction (see Tx),
+// an application talking to the database over its own connections will not see them
+type FixtureSession struct {
+	fm *FixtureManager
+	tx *sql.Tx
+	// Context given to Begin, used by LoadYAML
+	ctx     context.Context
+	pending map[string][]trackedRecord
+}
+
+// Begin starts a fixture session on a new transaction
+// Session settings such as StatementTimeout apply to every statement of the session
+func (fm *FixtureManager) Begin(ctx context.Context) (*FixtureSession, error) {
+	tx, err := fm.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin session transaction: %w", err)
+	}
+
+	return &FixtureSession{
+		fm:      fm,
+		tx:      tx,
+		ctx:     ctx,
+		pending: make(map[string][]trackedRecord),
+	}, nil
+}
+
+// Tx returns the session transaction, to be passed to the code under test
+func (s *FixtureSession) Tx() *sql.Tx {
+	return s.tx
+}
+
+// LoadYAML loads fixtures from a YAML file within the session transaction, with the context given to Begin
+func (s *FixtureSession) LoadYAML(fixturePath string) error {
+	return s.LoadYAMLContext(s.ctx, fixturePath)
+}
+
+// LoadYAMLContext loads fixtures like LoadYAML, cancelling the load when ctx is done
+func (s *FixtureSession) LoadYAMLContext(ctx context.Context, fixturePath string) error {
+	fsys, name := osFile(fixturePath)
+	fixtures, order, err := s.fm.readFixtures(fsys, name, YAMLParser{})
+	if err != nil {
+		return err
+	}
+
+	load := &loadState{aliases: newAliasRegistry(), dependencies: orderDependencies(order)}
+	tableNames, err := s.fm.tableOrder(fixtures, load.dependencies)
+	if err != nil {
+		return err
+	}
+	return s.fm.insertTables(ctx, s.tx, fixturePath, fixtures, tableNames, s.pending, load)
+}
+
+// CountRows returns the number of rows in a table matching the given conditions, as seen by the session
+func (s *FixtureSession) CountRows(ctx context.Context, table string, where map[string]any) (int, error) {
+	return countRows(ctx, s.tx, s.fm.config.Dialect, table, where)
+}
+
+// Query runs a query within the session and returns every row as a column/value map
+func (s *FixtureSession) Query(ctx context.Context, query string, args ...any) ([]map[string]any, error) {
+	_, rows, err := queryRows(ctx, s.tx, query, args...)
+	return rows, err
+}
+
+// Exec runs a statement within the session
+func (s *FixtureSession) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.tx.ExecContext(ctx, query, args...)
+}
+
+// Rollback undoes everything done in the session
+// Calling it after Commit or a previous Rollback is a no-op
+func (s *FixtureSession) Rollback() error {
+	if err := s.tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return fmt.Errorf("failed to rollback session: %w", err)
+	}
+	return nil
+}
+
+// Commit persists the session, its fixture rows are then tracked for CleanupFixtures like any other load
+// In DryRun mode the session is rolled back instead, like every other load
+func (s *FixtureSession) Commit() error {
+	if err := s.fm.commit(s.tx); err != nil {
+		return fmt.Errorf("failed to commit session: %w", err)
+	}
+	s.fm.track(s.pending)
+	return nil
+}
@@ -0,0 +1,127 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSessionAppliesStatementTimeout(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.StatementTimeout = 1500 * time.Millisecond
+	fm, fake := newFakeManager(t, config)
+	fake.returnIDs()
+
+	session, err := fm.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	defer session.Rollback()
+
+	statements := fake.queryTexts("")
+	want := []string{"BEGIN", "SET LOCAL statement_timeout = 1500"}
+	if !slices.Equal(statements, want) {
+		t.Errorf("statements = %q, want %q", statements, want)
+	}
+}
+
+func TestSessionRollbackLeavesNothingTracked(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+
+	session, err := fm.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := session.LoadYAML(writeFixture(t, "users.yml", "users:\n  - name: alice\n")); err != nil {
+		t.Fatalf("LoadYAML() error = %v", err)
+	}
+	if err := session.Rollback(); err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if err := session.Rollback(); err != nil {
+		t.Errorf("second Rollback() error = %v, want a no-op", err)
+	}
+
+	if keys := fm.GetInsertedKeys("users"); keys != nil {
+		t.Errorf("GetInsertedKeys() = %v after rollback, want nothing tracked", keys)
+	}
+	if statements := fake.queryTexts("ROLLBACK"); len(statements) != 1 {
+		t.Errorf("rollbacks = %d, want 1", len(statements))
+	}
+}
+
+func TestSessionCommitTracksRows(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+
+	session, err := fm.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := session.LoadYAML(writeFixture(t, "users.yml", "users:\n  - name: alice\n")); err != nil {
+		t.Fatalf("LoadYAML() error = %v", err)
+	}
+	if err := session.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if keys := fm.GetInsertedKeys("users"); len(keys) != 1 {
+		t.Errorf("GetInsertedKeys() = %v after commit, want the session row", keys)
+	}
+}
+
+func TestSessionDryRunRollsBackOnCommit(t *testing.T) {
+	config := DefaultFixtureConfig()
+	config.DryRun = true
+	fm, fake := newFakeManager(t, config)
+	fake.returnIDs()
+
+	session, err := fm.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if err := session.LoadYAML(writeFixture(t, "users.yml", "users:\n  - id: 1\n    name: alice\n")); err != nil {
+		t.Fatalf("LoadYAML() error = %v", err)
+	}
+	if err := session.Commit(); err != nil {
+		t.Fatalf("Commit() error = %v", err)
+	}
+
+	if inserts := fake.queryTexts("INSERT"); len(inserts) != 0 {
+		t.Errorf("inserts = %q, want only logged statements in DryRun mode", inserts)
+	}
+	if commits := fake.queryTexts("COMMIT"); len(commits) != 0 {
+		t.Errorf("commits = %d, want the session rolled back in DryRun mode", len(commits))
+	}
+	if rollbacks := fake.queryTexts("ROLLBACK"); len(rollbacks) != 1 {
+		t.Errorf("rollbacks = %d, want 1", len(rollbacks))
+	}
+	// Like other dry-run loads the fixture keys are tracked for the logged cleanup
+	if keys := fm.GetInsertedKeys("users"); len(keys) != 1 || keys[0]["id"] != 1 {
+		t.Errorf("GetInsertedKeys() = %v, want the fixture key", keys)
+	}
+}
+
+func TestSessionLoadYAMLContext(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+
+	session, err := fm.Begin(context.Background())
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	defer session.Rollback()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = session.LoadYAMLContext(ctx, writeFixture(t, "users.yml", "users:\n  - name: alice\n"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadYAMLContext() error = %v, want context.Canceled", err)
+	}
+	if inserts := fake.queryTexts("INSERT"); len(inserts) != 0 {
+		t.Errorf("inserts = %q, want none after the context is done", inserts)
+	}
+}
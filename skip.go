@@ -0,0 +1,30 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+// skipPingTimeout bounds how long SkipIfNoDB waits for the database
+const skipPingTimeout = 2 * time.Second
+
+// SkipIfNoDB skips the test when the database at dsn cannot be reached
+// It uses a short ping timeout so environments without a database skip quickly
+func SkipIfNoDB(t testing.TB, dsn string) {
+	t.Helper()
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Skipf("Skipping test: failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), skipPingTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Skipf("Skipping test: database is not reachable: %v", err)
+	}
+}
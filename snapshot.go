@@ -0,0 +1,190 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// snapshotSuffix is appended to table names to name the tables holding their snapshot
+const snapshotSuffix = "_testkit_snapshot"
+
+// Snapshot copies the current rows of the tables into snapshot tables, so Restore can bring them back, PostgreSQL only
+// Without tables it snapshots the tables fixtures were loaded into; a new snapshot replaces the previous one
+// The copies are regular tables, so Restore also reverts rows the application wrote through its own connections
+func (fm *FixtureManager) Snapshot(ctx context.Context, tables ...string) error {
+	if fm.config.Dialect != DialectPostgres {
+		return errors.New("snapshots require PostgreSQL")
+	}
+	if len(tables) == 0 {
+		tables = fm.LoadedTables()
+	}
+	// Restore inserts the rows back in this order, so foreign keys between the tables hold
+	ordered, err := fm.dependencyOrder(tables, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fm.DropSnapshot(ctx); err != nil {
+		return err
+	}
+
+	tx, err := fm.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin snapshot transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Printf("failed to rollback snapshot transaction: %v", err)
+		}
+	}()
+
+	dialect := fm.config.Dialect
+	for _, tableName := range ordered {
+		//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+		query := fmt.Sprintf("CREATE UNLOGGED TABLE %s AS SELECT * FROM %s",
+			dialect.QuoteIdentifier(tableName+snapshotSuffix), dialect.QuoteIdentifier(tableName))
+		if err := fm.exec(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to snapshot table %s: %w", tableName, err)
+		}
+	}
+
+	if err := fm.commit(tx); err != nil {
+		return fmt.Errorf("failed to commit snapshot transaction: %w", err)
+	}
+
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	fm.snapshotTables = ordered
+	return nil
+}
+
+// Restore replaces the rows of the snapshot tables with the rows recorded by Snapshot, in one transaction
+// The tables are truncated together without CASCADE, so a table referencing them that is not part of the snapshot
+// fails the restore instead of being emptied; sequences are not reset, new rows keep getting fresh IDs
+// Generated columns are computed again rather than copied
+func (fm *FixtureManager) Restore(ctx context.Context) error {
+	fm.mu.Lock()
+	tables := slices.Clone(fm.snapshotTables)
+	fm.mu.Unlock()
+	if len(tables) == 0 {
+		return errors.New("no snapshot to restore, call Snapshot first")
+	}
+
+	tx, err := fm.begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin restore transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Printf("failed to rollback restore transaction: %v", err)
+		}
+	}()
+
+	dialect := fm.config.Dialect
+	//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+	query := fmt.Sprintf("TRUNCATE TABLE %s", strings.Join(dialect.quoteIdentifiers(tables), ", "))
+	if err := fm.exec(ctx, tx, query); err != nil {
+		return fmt.Errorf("failed to truncate tables %s: %w", strings.Join(tables, ", "), err)
+	}
+	for _, tableName := range tables {
+		columns, identity, err := restoreColumns(ctx, tx, tableName)
+		if err != nil {
+			return err
+		}
+		var overriding string
+		if identity {
+			// Keeps the recorded values of GENERATED ALWAYS identity columns
+			overriding = " OVERRIDING SYSTEM VALUE"
+		}
+		quoted := strings.Join(dialect.quoteIdentifiers(columns), ", ")
+		//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+		query := fmt.Sprintf("INSERT INTO %s (%s)%s SELECT %s FROM %s", dialect.QuoteIdentifier(tableName),
+			quoted, overriding, quoted, dialect.QuoteIdentifier(tableName+snapshotSuffix))
+		if err := fm.exec(ctx, tx, query); err != nil {
+			return fmt.Errorf("failed to restore table %s: %w", tableName, err)
+		}
+	}
+
+	if err := fm.commit(tx); err != nil {
+		return fmt.Errorf("failed to commit restore transaction: %w", err)
+	}
+	return nil
+}
+
+// restoreColumns returns the columns of a table Restore writes, in table order, leaving out generated columns that
+// the database computes itself. identity reports a GENERATED ALWAYS identity column, which needs
+// OVERRIDING SYSTEM VALUE
+func restoreColumns(ctx context.Context, q querier, tableName string) (columns []string, identity bool, err error) {
+	schema, name := splitTableName(tableName)
+	rows, err := q.QueryContext(ctx, `
+		SELECT column_name, is_generated, COALESCE(identity_generation, '')
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE($1::text, current_schema()) AND table_name = $2
+		ORDER BY ordinal_position`,
+		schema, name,
+	)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query columns of table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var column, generated, identityGeneration string
+		if err := rows.Scan(&column, &generated, &identityGeneration); err != nil {
+			return nil, false, fmt.Errorf("failed to scan column of table %s: %w", tableName, err)
+		}
+		if generated == "ALWAYS" {
+			continue
+		}
+		columns = append(columns, column)
+		identity = identity || identityGeneration == "ALWAYS"
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("failed to read columns of table %s: %w", tableName, err)
+	}
+	if len(columns) == 0 {
+		return nil, false, fmt.Errorf("table %s has no columns to restore", tableName)
+	}
+	return columns, identity, nil
+}
+
+// DropSnapshot drops the snapshot tables, it does nothing without a snapshot
+func (fm *FixtureManager) DropSnapshot(ctx context.Context) error {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	if len(fm.snapshotTables) == 0 {
+		return nil
+	}
+
+	snapshots := make([]string, len(fm.snapshotTables))
+	for i, tableName := range fm.snapshotTables {
+		snapshots[i] = tableName + snapshotSuffix
+	}
+	//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+	query := fmt.Sprintf("DROP TABLE IF EXISTS %s",
+		strings.Join(fm.config.Dialect.quoteIdentifiers(snapshots), ", "))
+	if fm.config.DryRun {
+		logDryRun(query, nil)
+	} else if _, err := fm.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to drop snapshot tables: %w", err)
+	}
+	fm.snapshotTables = nil
+	return nil
+}
+
+// Snapshot records the rows of the tables fixtures were loaded into in the primary database, see Restore
+// Call it once the shared fixtures are loaded, e.g. in BeforeAll
+func (r *TestRunner) Snapshot() error {
+	return r.fixtureManager.Snapshot(context.Background())
+}
+
+// Restore brings the primary database tables back to the rows recorded by Snapshot
+// e.g. t.Cleanup(func() { _ = runner.Restore() }) gives every test the same starting data
+func (r *TestRunner) Restore() error {
+	return r.fixtureManager.Restore(context.Background())
+}
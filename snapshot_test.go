@@ -0,0 +1,112 @@
+package testkit
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// restoreColumnRows returns the information_schema.columns answer read by Restore
+func restoreColumnRows(columns ...[]any) *fakeRows {
+	return &fakeRows{columns: []string{"column_name", "is_generated", "coalesce"}, rows: columns}
+}
+
+func TestRestoreSkipsGeneratedColumns(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fm.ConfigureTableDependencies("orders", []string{"users"})
+	fake.onQuery = func(query string, args []any) (*fakeRows, error) {
+		if !strings.Contains(query, "is_generated") {
+			return nil, nil
+		}
+		if args[1] == "users" {
+			return restoreColumnRows(
+				[]any{"id", "NEVER", "ALWAYS"},
+				[]any{"name", "NEVER", ""},
+				[]any{"name_lower", "ALWAYS", ""},
+			), nil
+		}
+		return restoreColumnRows([]any{"id", "NEVER", "BY DEFAULT"}, []any{"user_id", "NEVER", ""}), nil
+	}
+
+	ctx := context.Background()
+	if err := fm.Snapshot(ctx, "orders", "users"); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	fake.reset()
+	if err := fm.Restore(ctx); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	want := []string{
+		`TRUNCATE TABLE "users", "orders"`,
+		`INSERT INTO "users" ("id", "name") OVERRIDING SYSTEM VALUE SELECT "id", "name" FROM "users_testkit_snapshot"`,
+		`INSERT INTO "orders" ("id", "user_id") SELECT "id", "user_id" FROM "orders_testkit_snapshot"`,
+	}
+	var got []string
+	for _, statement := range fake.queryTexts("") {
+		if strings.HasPrefix(statement, "TRUNCATE") || strings.HasPrefix(statement, "INSERT") {
+			got = append(got, statement)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("restore statements = %q, want %q", got, want)
+	}
+}
+
+func TestTruncateAllKeepsSnapshotTables(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.onQuery = func(query string, _ []any) (*fakeRows, error) {
+		if !strings.Contains(query, "information_schema.tables") {
+			return nil, nil
+		}
+		return &fakeRows{columns: []string{"table_name"}, rows: [][]any{
+			{"schema_migrations"}, {"users"}, {"users_testkit_snapshot"},
+		}}, nil
+	}
+
+	if err := fm.TruncateAll("schema_migrations"); err != nil {
+		t.Fatalf("TruncateAll() error = %v", err)
+	}
+	want := []string{`TRUNCATE TABLE "users" RESTART IDENTITY CASCADE`}
+	if got := fake.queryTexts("TRUNCATE"); !slices.Equal(got, want) {
+		t.Errorf("truncates = %q, want %q", got, want)
+	}
+}
+
+func TestSnapshotRestoreGeneratedColumns(t *testing.T) {
+	db := newPostgresDB(t,
+		`CREATE TABLE users (
+			id int GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+			name text NOT NULL,
+			name_lower text GENERATED ALWAYS AS (lower(name)) STORED
+		)`,
+	)
+	fm := NewFixtureManager(db)
+	if err := fm.LoadYAMLFixtures(writeFixture(t, "users.yml", "users:\n  - name: Alice\n")); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	t.Cleanup(func() { _ = fm.DropSnapshot(context.Background()) })
+
+	ctx := context.Background()
+	if err := fm.Snapshot(ctx); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if _, err := db.Exec("UPDATE users SET name = 'Bob'"); err != nil {
+		t.Fatalf("failed to change users: %v", err)
+	}
+	if err := fm.TruncateAll(); err != nil {
+		t.Fatalf("TruncateAll() error = %v", err)
+	}
+	if err := fm.Restore(ctx); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	var name, lower string
+	if err := db.QueryRow("SELECT name, name_lower FROM users").Scan(&name, &lower); err != nil {
+		t.Fatalf("failed to read restored user: %v", err)
+	}
+	if name != "Alice" || lower != "alice" {
+		t.Errorf("restored user = %s/%s, want Alice/alice", name, lower)
+	}
+}
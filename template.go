@@ -0,0 +1,111 @@
+package testkit
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateFuncs are available to fixture templates in addition to the text/template builtins
+var templateFuncs = template.FuncMap{
+	// seq returns 1..n, e.g. {{range $i := seq 3}} to generate numbered rows and aliases
+	"seq": func(n int) ([]int, error) {
+		if n < 0 {
+			return nil, fmt.Errorf("seq count must not be negative, got %d", n)
+		}
+		numbers := make([]int, n)
+		for i := range numbers {
+			numbers[i] = i + 1
+		}
+		return numbers, nil
+	},
+}
+
+// SetTemplateData enables text/template rendering of fixture files with the given data, nil disables it
+// Files are rendered before parsing, so fixtures containing a literal "{{" are only affected once enabled
+// Missing keys follow OnMissingVar: an error by default, an empty string with MissingVarEmpty and the template's
+// "<no value>" with MissingVarKeep
+func (fm *FixtureManager) SetTemplateData(data map[string]any) {
+	fm.templateData = data
+}
+
+// renderTemplate renders fixture file content when templating is enabled
+func (fm *FixtureManager) renderTemplate(name string, content []byte) ([]byte, error) {
+	if fm.templateData == nil {
+		return content, nil
+	}
+
+	tmpl, err := template.New(name).Funcs(templateFuncs).Funcs(template.FuncMap{emptyNilFunc: emptyNil}).
+		Option(fm.missingKeyOption()).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fixture template %s: %w", name, err)
+	}
+	if fm.config.OnMissingVar == MissingVarEmpty {
+		for _, t := range tmpl.Templates() {
+			if t.Tree != nil {
+				pipeActions(t.Tree, t.Tree.Root, emptyNilFunc)
+			}
+		}
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, fm.templateData); err != nil {
+		return nil, fmt.Errorf("failed to render fixture template %s: %w", name, err)
+	}
+	return rendered.Bytes(), nil
+}
+
+// missingKeyOption returns the text/template missingkey option matching OnMissingVar
+// MissingVarEmpty keeps the default, which unlike missingkey=zero also lets nested fields of a missing key through,
+// and pipes the printed values through emptyNil
+func (fm *FixtureManager) missingKeyOption() string {
+	switch fm.config.OnMissingVar {
+	case MissingVarEmpty, MissingVarKeep:
+		return "missingkey=default"
+	default:
+		return "missingkey=error"
+	}
+}
+
+// emptyNilFunc is the name of emptyNil in fixture templates
+const emptyNilFunc = "emptyNil"
+
+// emptyNil returns an empty string for nil, which text/template prints as "<no value>", and v otherwise
+func emptyNil(v any) any {
+	if v == nil {
+		return ""
+	}
+	return v
+}
+
+// pipeActions appends the function to the pipeline of every action printing a value below node
+func pipeActions(tree *parse.Tree, node parse.Node, function string) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, child := range node.Nodes {
+			pipeActions(tree, child, function)
+		}
+	case *parse.ActionNode:
+		if len(node.Pipe.Decl) == 0 {
+			identifier := parse.NewIdentifier(function).SetTree(tree).SetPos(node.Pos)
+			node.Pipe.Cmds = append(node.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Pos:      node.Pos,
+				Args:     []parse.Node{identifier},
+			})
+		}
+	case *parse.IfNode:
+		pipeActions(tree, node.List, function)
+		pipeActions(tree, node.ElseList, function)
+	case *parse.RangeNode:
+		pipeActions(tree, node.List, function)
+		pipeActions(tree, node.ElseList, function)
+	case *parse.WithNode:
+		pipeActions(tree, node.List, function)
+		pipeActions(tree, node.ElseList, function)
+	}
+}
@@ -0,0 +1,114 @@
+package testkit
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateGeneratedAliases(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+	fm.SetTemplateData(map[string]any{"TenantID": 7})
+
+	// Children are listed first, the references still load users before orders
+	fixture := writeFixture(t, "seed.yml", `
+orders:
+{{- range $i := seq 3}}
+  - user_id: $users.user_{{$i}}.id
+{{- end}}
+users:
+{{- range $i := seq 3}}
+  - _alias: user_{{$i}}
+    tenant_id: {{$.TenantID}}
+{{- end}}
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queries("INSERT")
+	if len(inserts) != 6 {
+		t.Fatalf("inserts = %d, want 6", len(inserts))
+	}
+	// The fake database hands out ids 1, 2, 3 to the users in template order
+	for i, insert := range inserts {
+		table := strings.Trim(strings.Fields(insert.Query)[2], `"`)
+		switch {
+		case i < 3 && (table != "users" || insert.Args[0] != 7):
+			t.Errorf("insert %d = %s %v, want a user of tenant 7", i, table, insert.Args)
+		case i >= 3 && (table != "orders" || insert.Args[0] != int64(i-2)):
+			t.Errorf("insert %d = %s %v, want an order of user %d", i, table, insert.Args, i-2)
+		}
+	}
+}
+
+func TestTemplateAliasesWithinOneTable(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+	fm.SetTemplateData(map[string]any{})
+
+	fixture := writeFixture(t, "employees.yml", `
+employees:
+  - _alias: ceo
+    name: ceo
+{{- range $i := seq 2}}
+  - _alias: emp_{{$i}}
+    manager_id: $employees.ceo.id
+{{- end}}
+`)
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	inserts := fake.queries("INSERT")
+	if len(inserts) != 3 || inserts[1].Args[0] != int64(1) || inserts[2].Args[0] != int64(1) {
+		t.Errorf("inserts = %v, want two employees managed by id 1", inserts)
+	}
+}
+
+func TestTemplateAliasReferencedBeforeItsRow(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+	fm.SetTemplateData(map[string]any{})
+
+	fixture := writeFixture(t, "employees.yml", `
+employees:
+{{- range $i := seq 2}}
+  - _alias: emp_{{$i}}
+    manager_id: $employees.ceo.id
+{{- end}}
+  - _alias: ceo
+    name: ceo
+`)
+	err := fm.LoadYAMLFixtures(fixture)
+	if err == nil || !strings.Contains(err.Error(), "no row aliased ceo in table employees") {
+		t.Errorf("LoadYAMLFixtures() error = %v, want the alias referenced before its row", err)
+	}
+}
+
+func TestTemplateSeq(t *testing.T) {
+	fm, _ := newFakeManager(t, nil)
+	fm.SetTemplateData(map[string]any{})
+
+	rendered, err := fm.renderTemplate("seq.yml", []byte("{{range seq 3}}{{.}},{{end}}|{{range seq 0}}x{{end}}"))
+	if err != nil {
+		t.Fatalf("renderTemplate() error = %v", err)
+	}
+	if string(rendered) != "1,2,3,|" {
+		t.Errorf("renderTemplate() = %q, want %q", rendered, "1,2,3,|")
+	}
+
+	_, err = fm.renderTemplate("seq.yml", []byte("{{range seq -1}}{{end}}"))
+	if err == nil || !strings.Contains(err.Error(), "seq count must not be negative") {
+		t.Errorf("renderTemplate(seq -1) error = %v, want the negative count", err)
+	}
+}
+
+func TestTemplateDisabledKeepsBraces(t *testing.T) {
+	fm, _ := newFakeManager(t, nil)
+
+	content := []byte("notes:\n  - body: \"{{ not a template\"\n")
+	rendered, err := fm.renderTemplate("notes.yml", content)
+	if err != nil || string(rendered) != string(content) {
+		t.Errorf("renderTemplate() = %q, %v, want the content unchanged", rendered, err)
+	}
+}
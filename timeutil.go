@@ -0,0 +1,32 @@
+package testkit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timestampLayouts are the layouts accepted for timestamps written as strings, matching what YAML resolves unquoted
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseTimestamp parses a timestamp string in any of the accepted layouts
+func parseTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+// intervalLiteral formats a duration as a PostgreSQL interval input
+func intervalLiteral(d time.Duration) string {
+	return fmt.Sprintf("%d microseconds", d.Microseconds())
+}
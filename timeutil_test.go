@@ -0,0 +1,47 @@
+package testkit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{in: "2023-01-01T00:00:00Z", want: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{in: "2023-01-01T10:30:00.5+02:00", want: time.Date(2023, 1, 1, 8, 30, 0, 5e8, time.UTC)},
+		{in: "2023-01-01T10:30:00", want: time.Date(2023, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{in: "2023-01-01 10:30:00Z", want: time.Date(2023, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{in: "2023-01-01 10:30:00", want: time.Date(2023, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{in: " 2023-01-01 ", want: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.in)
+		if err != nil {
+			t.Errorf("parseTimestamp(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimestamp(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	if _, err := parseTimestamp("yesterday"); err == nil {
+		t.Error("parseTimestamp(yesterday) error = nil, want an invalid timestamp error")
+	}
+}
+
+func TestIntervalLiteral(t *testing.T) {
+	tests := map[time.Duration]string{
+		24 * time.Hour:          "86400000000 microseconds",
+		1500 * time.Millisecond: "1500000 microseconds",
+		-time.Minute:            "-60000000 microseconds",
+	}
+	for d, want := range tests {
+		if got := intervalLiteral(d); got != want {
+			t.Errorf("intervalLiteral(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
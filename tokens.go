@@ -0,0 +1,127 @@
+package testkit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Built-in value tokens
+const (
+	nowToken   = "NOW()"
+	todayToken = "TODAY()"
+)
+
+// tokenOffsetSuffix matches the signed offset that may follow a token, e.g. "+1h", "-7d" or "+1h30m"
+var tokenOffsetSuffix = regexp.MustCompile(`^\s*[+-]\s*(\d+(\.\d+)?[a-zµ]+)+$`)
+
+// ValueFunc produces the value of a fixture token
+// It receives the whole string value, e.g. "NOW()+1h", so it can parse arguments following the token
+type ValueFunc func(token string) (any, error)
+
+// RegisterValueFunc registers the function producing the value of fixture strings using token, e.g. "DAYS_AGO("
+// Registered tokens take precedence over NOW() and TODAY(), register them before loading fixtures
+func (fm *FixtureManager) RegisterValueFunc(token string, fn ValueFunc) {
+	if fm.valueFuncs == nil {
+		fm.valueFuncs = make(map[string]ValueFunc)
+	}
+	fm.valueFuncs[token] = fn
+}
+
+// valueFunc returns the function producing the value of a string, if it uses a token
+func (fm *FixtureManager) valueFunc(value string) (ValueFunc, bool) {
+	var match string
+	var fn ValueFunc
+	for token, f := range fm.valueFuncs {
+		if len(token) > len(match) && usesToken(value, token) {
+			match, fn = token, f
+		}
+	}
+	if fn != nil {
+		return fn, true
+	}
+
+	switch {
+	case usesToken(value, nowToken):
+		return fm.nowTokenValue, true
+	case usesToken(value, todayToken):
+		return fm.todayTokenValue, true
+	}
+	return nil, false
+}
+
+// usesToken reports whether a string value is a token, optionally followed by an offset, or a call of a token
+// ending in "(", rather than text that happens to start with the token
+func usesToken(value, token string) bool {
+	rest, ok := strings.CutPrefix(value, token)
+	switch {
+	case !ok:
+		return false
+	case rest == "" || tokenOffsetSuffix.MatchString(rest):
+		return true
+	default:
+		return strings.HasSuffix(token, "(") && strings.HasSuffix(rest, ")")
+	}
+}
+
+// nowTokenValue resolves NOW() with an optional offset such as NOW()+1h or NOW()-7d
+func (fm *FixtureManager) nowTokenValue(token string) (any, error) {
+	offset, err := tokenOffset(token, nowToken)
+	if err != nil {
+		return nil, err
+	}
+	if offset == 0 {
+		return fm.nowValue(), nil
+	}
+	if fm.config.NowBinding == NowServerTime {
+		return sqlExpression(fm.config.Dialect.nowExpression(offset)), nil
+	}
+	return time.Now().Add(offset), nil
+}
+
+// todayTokenValue resolves TODAY(), the start of the current day, with an optional offset such as TODAY()-1d
+func (fm *FixtureManager) todayTokenValue(token string) (any, error) {
+	offset, err := tokenOffset(token, todayToken)
+	if err != nil {
+		return nil, err
+	}
+	if fm.config.NowBinding == NowServerTime {
+		return sqlExpression(fm.config.Dialect.todayExpression(offset)), nil
+	}
+	now := time.Now()
+	year, month, day := now.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Add(offset), nil
+}
+
+// tokenOffset parses the signed offset following a built-in token, a Go duration or a number of days such as "7d"
+func tokenOffset(token, name string) (time.Duration, error) {
+	rest := strings.TrimSpace(strings.TrimPrefix(token, name))
+	if rest == "" {
+		return 0, nil
+	}
+
+	sign := time.Duration(1)
+	switch rest[0] {
+	case '+':
+	case '-':
+		sign = -1
+	default:
+		return 0, fmt.Errorf("invalid %s offset %q, expected e.g. %s+1h", name, rest, name)
+	}
+	rest = strings.TrimSpace(rest[1:])
+
+	if days, ok := strings.CutSuffix(rest, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s offset %q: %w", name, rest, err)
+		}
+		return sign * time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s offset %q: %w", name, rest, err)
+	}
+	return sign * d, nil
+}
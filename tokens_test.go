@@ -0,0 +1,156 @@
+package testkit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNowServerTimeExpressions(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		token   string
+		want    sqlExpression
+	}{
+		{name: "postgres now", dialect: DialectPostgres, token: "NOW()", want: "NOW()"},
+		{name: "postgres now offset", dialect: DialectPostgres, token: "NOW()+1h",
+			want: "NOW() + interval '3600000000 microseconds'"},
+		{name: "postgres today", dialect: DialectPostgres, token: "TODAY()", want: "CURRENT_DATE"},
+		{name: "postgres today offset", dialect: DialectPostgres, token: "TODAY()-1d",
+			want: "CURRENT_DATE + interval '-86400000000 microseconds'"},
+		{name: "mysql now", dialect: DialectMySQL, token: "NOW()", want: "NOW()"},
+		{name: "mysql now offset", dialect: DialectMySQL, token: "NOW()-90s",
+			want: "DATE_ADD(NOW(), INTERVAL -90000000 MICROSECOND)"},
+		{name: "mysql today offset", dialect: DialectMySQL, token: "TODAY()+7d",
+			want: "DATE_ADD(CURRENT_DATE, INTERVAL 604800000000 MICROSECOND)"},
+		{name: "sqlite now", dialect: DialectSQLite, token: "NOW()", want: "datetime('now')"},
+		{name: "sqlite now offset", dialect: DialectSQLite, token: "NOW()+1.5s", want: "datetime('now', '+1.5 seconds')"},
+		{name: "sqlite today", dialect: DialectSQLite, token: "TODAY()", want: "date('now')"},
+		{name: "sqlite today offset", dialect: DialectSQLite, token: "TODAY()-1d",
+			want: "datetime('now', 'start of day', '-86400 seconds')"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.Dialect = tt.dialect
+			config.NowBinding = NowServerTime
+			fm, _ := newFakeManager(t, config)
+
+			fn, ok := fm.valueFunc(tt.token)
+			if !ok {
+				t.Fatalf("valueFunc(%q) found no token", tt.token)
+			}
+			got, err := fn(tt.token)
+			if err != nil {
+				t.Fatalf("token %q error = %v", tt.token, err)
+			}
+			if got != tt.want {
+				t.Errorf("token %q = %v, want %v", tt.token, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNowClientTimeOffsets(t *testing.T) {
+	fm, _ := newFakeManager(t, nil)
+
+	before := time.Now()
+	value, err := fm.nowTokenValue("NOW() - 7d")
+	if err != nil {
+		t.Fatalf("nowTokenValue() error = %v", err)
+	}
+	got, ok := value.(time.Time)
+	if !ok {
+		t.Fatalf("nowTokenValue() = %T, want time.Time", value)
+	}
+	if want := before.Add(-7 * 24 * time.Hour); got.Before(want) || got.After(time.Now().Add(-7*24*time.Hour)) {
+		t.Errorf("nowTokenValue() = %v, want seven days before %v", got, before)
+	}
+
+	value, err = fm.todayTokenValue("TODAY()")
+	if err != nil {
+		t.Fatalf("todayTokenValue() error = %v", err)
+	}
+	if today := value.(time.Time); today.Hour() != 0 || today.Minute() != 0 || today.YearDay() != time.Now().YearDay() {
+		t.Errorf("todayTokenValue() = %v, want the start of today", today)
+	}
+}
+
+func TestTokenOffsetErrors(t *testing.T) {
+	for _, token := range []string{"NOW()+", "NOW()+1x", "NOW()+xd"} {
+		if _, err := tokenOffset(token, nowToken); err == nil {
+			t.Errorf("tokenOffset(%q) error = nil, want an invalid offset", token)
+		}
+	}
+}
+
+func TestValueFuncMatching(t *testing.T) {
+	fm, _ := newFakeManager(t, nil)
+	fm.RegisterValueFunc("DAYS_AGO(", func(string) (any, error) { return "days", nil })
+	fm.RegisterValueFunc("UUID()", func(string) (any, error) { return "uuid", nil })
+	fm.RegisterValueFunc("UUID()_V7", func(string) (any, error) { return "uuid v7", nil })
+
+	// want is the produced value, "time" for any time.Time and empty for a plain string
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "NOW() is great"},
+		{value: "NOW()!"},
+		{value: "NOW()+later"},
+		{value: "TODAY() or tomorrow"},
+		{value: "NOW()", want: "time"},
+		{value: "NOW()+1h", want: "time"},
+		{value: "NOW() - 7d", want: "time"},
+		{value: "NOW()+1h30m", want: "time"},
+		{value: "TODAY()-1d", want: "time"},
+		{value: "DAYS_AGO(3)", want: "days"},
+		{value: "DAYS_AGO(3) maybe"},
+		{value: "UUID()", want: "uuid"},
+		{value: "UUID()_V7", want: "uuid v7"},
+		{value: "UUID() of the user"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			fn, ok := fm.valueFunc(tt.value)
+			if ok != (tt.want != "") {
+				t.Fatalf("valueFunc(%q) matched = %v, want %v", tt.value, ok, tt.want != "")
+			}
+			if !ok {
+				return
+			}
+			produced, err := fn(tt.value)
+			if err != nil {
+				t.Fatalf("token %q error = %v", tt.value, err)
+			}
+			if _, isTime := produced.(time.Time); isTime {
+				produced = "time"
+			}
+			if produced != tt.want {
+				t.Errorf("valueFunc(%q) produced %v, want %v", tt.value, produced, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadKeepsTextStartingWithToken(t *testing.T) {
+	fm, fake := newFakeManager(t, nil)
+	fake.returnIDs()
+
+	fixture := writeFixture(t, "posts.yml", "posts:\n  - title: NOW() is great\n    published_at: NOW()-1h\n")
+	if err := fm.LoadYAMLFixtures(fixture); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+
+	inserts := fake.queries("INSERT")
+	if len(inserts) != 1 || len(inserts[0].Args) != 2 {
+		t.Fatalf("inserts = %v, want one row with two values", inserts)
+	}
+	// Columns are inserted in name order: published_at, title
+	if _, ok := inserts[0].Args[0].(time.Time); !ok {
+		t.Errorf("published_at = %v, want a time", inserts[0].Args[0])
+	}
+	if title := inserts[0].Args[1]; title != "NOW() is great" {
+		t.Errorf("title = %v, want the text as written", title)
+	}
+}
@@ -0,0 +1,83 @@
+package testkit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+)
+
+// TruncateTables empties the tables with TRUNCATE ... RESTART IDENTITY CASCADE, PostgreSQL only
+// Unlike CleanupFixtures it also removes rows the application under test created
+// CASCADE empties tables referencing them as well, even when they are not listed
+func (fm *FixtureManager) TruncateTables(tables ...string) error {
+	if len(tables) == 0 {
+		return nil
+	}
+
+	tx, err := fm.begin(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to begin truncate transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			log.Printf("failed to rollback truncate transaction: %v", err)
+		}
+	}()
+
+	// A single statement truncates the tables together, so foreign keys between them do not matter
+	//nolint:gosec // G201: SQL string formatting is safe here with quoted identifiers
+	query := fmt.Sprintf("TRUNCATE TABLE %s RESTART IDENTITY CASCADE",
+		strings.Join(fm.config.Dialect.quoteIdentifiers(tables), ", "))
+	if err := fm.exec(context.Background(), tx, query); err != nil {
+		return fmt.Errorf("failed to truncate tables %s: %w", strings.Join(tables, ", "), err)
+	}
+
+	if err := fm.commit(tx); err != nil {
+		return fmt.Errorf("failed to commit truncate transaction: %w", err)
+	}
+
+	// The tracked rows are gone, cleanup does not need to delete them anymore
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	for _, tableName := range tables {
+		delete(fm.insertedRecords, tableName)
+		delete(fm.largeTables, tableName)
+	}
+
+	return nil
+}
+
+// TruncateAll truncates every table of the current schema except the excluded ones,
+// e.g. TruncateAll("schema_migrations") to keep the migration version table
+// The tables holding a Snapshot are always kept, so Restore still works afterwards
+func (fm *FixtureManager) TruncateAll(exclude ...string) error {
+	rows, err := fm.db.QueryContext(context.Background(), `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+		ORDER BY table_name`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var tableName string
+		if err := rows.Scan(&tableName); err != nil {
+			return fmt.Errorf("failed to scan table name: %w", err)
+		}
+		if !slices.Contains(exclude, tableName) && !strings.HasSuffix(tableName, snapshotSuffix) {
+			tables = append(tables, tableName)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read table names: %w", err)
+	}
+
+	return fm.TruncateTables(tables...)
+}
@@ -0,0 +1,77 @@
+package testkit
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// runIDValue is the !runid fixture value, replaced with the manager's run ID at load time
+type runIDValue struct{}
+
+// RunID returns the UUID generated when the manager was created, the value of !runid fixture values
+// Stamp rows with it to tell them apart from rows of other runs sharing the database
+func (fm *FixtureManager) RunID() string {
+	return fm.runID
+}
+
+// RegisterDecryptor sets the function decrypting !secret fixture values before they are bound
+// testkit does not implement any encryption itself, e.g. wrap a SOPS or KMS client
+func (fm *FixtureManager) RegisterDecryptor(decrypt func(ciphertext string) (string, error)) {
+	fm.decryptor = decrypt
+}
+
+// resolveDeferredValues returns the record with the values that depend on the manager resolved
+// These are the !secret and !runid directives, which the parser cannot resolve on its own
+func (fm *FixtureManager) resolveDeferredValues(record map[string]any) (map[string]any, error) {
+	var resolved map[string]any
+	for column, value := range record {
+		switch v := value.(type) {
+		case secretValue:
+			if fm.decryptor == nil {
+				return nil, fmt.Errorf("column %s uses !secret but no decryptor is registered", column)
+			}
+			plaintext, err := fm.decryptor(string(v))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt column %s: %w", column, err)
+			}
+			value = plaintext
+		case runIDValue:
+			value = fm.runID
+		case string:
+			fn, ok := fm.valueFunc(v)
+			if !ok {
+				continue
+			}
+			var err error
+			if value, err = fn(v); err != nil {
+				return nil, fmt.Errorf("column %s: %w", column, err)
+			}
+		default:
+			continue
+		}
+
+		if resolved == nil {
+			resolved = copyRecord(record)
+		}
+		resolved[column] = value
+	}
+
+	if resolved == nil {
+		return record, nil
+	}
+	return resolved, nil
+}
+
+// newUUID returns a random version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:]) // Never fails, crypto/rand aborts the program instead
+	return formatUUID(b)
+}
+
+// formatUUID formats random bytes as a version 4 UUID
+func formatUUID(b [16]byte) string {
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,94 @@
+package testkit
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// MissingVarMode controls what happens when a fixture references an undefined variable
+type MissingVarMode int
+
+const (
+	// MissingVarError fails the load, naming the variable (default)
+	MissingVarError MissingVarMode = iota
+	// MissingVarEmpty replaces the reference with an empty string
+	MissingVarEmpty
+	// MissingVarKeep leaves the ${VAR} reference in place
+	MissingVarKeep
+)
+
+// varReference matches ${VAR} references in fixture strings
+var varReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandFixtureVars replaces ${VAR} references in every string value of the fixtures, including nested ones
+func (fm *FixtureManager) expandFixtureVars(fixtures TableFixtures) error {
+	for tableName, records := range fixtures {
+		for i, record := range records {
+			for column, value := range record {
+				expanded, err := fm.expandValue(value)
+				if err != nil {
+					return fmt.Errorf("table %s row %d column %s: %w", tableName, i, column, err)
+				}
+				record[column] = expanded
+			}
+		}
+	}
+	return nil
+}
+
+// expandValue expands variable references in strings, maps and lists
+func (fm *FixtureManager) expandValue(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return fm.expandString(v)
+	case map[string]any:
+		for key, item := range v {
+			expanded, err := fm.expandValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = expanded
+		}
+		return v, nil
+	case []any:
+		for i, item := range v {
+			expanded, err := fm.expandValue(item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = expanded
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandString replaces the ${VAR} references of a single string
+func (fm *FixtureManager) expandString(value string) (string, error) {
+	var missing string
+	expanded := varReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := varReference.FindStringSubmatch(reference)[1]
+		if resolved, ok := os.LookupEnv(name); ok {
+			return resolved
+		}
+
+		switch fm.config.OnMissingVar {
+		case MissingVarEmpty:
+			return ""
+		case MissingVarKeep:
+			return reference
+		default:
+			if missing == "" {
+				missing = name
+			}
+			return reference
+		}
+	})
+
+	if missing != "" {
+		return "", fmt.Errorf("undefined variable %s", missing)
+	}
+	return expanded, nil
+}
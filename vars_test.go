@@ -0,0 +1,133 @@
+package testkit
+
+import (
+	"strings"
+	"testing"
+)
+
+// insertedValues loads a fixture file and returns the values bound by its single INSERT
+func insertedValues(t *testing.T, fm *FixtureManager, fake *fakeDB, fixturePath string) []any {
+	t.Helper()
+
+	fake.returnIDs()
+	if err := fm.LoadYAMLFixtures(fixturePath); err != nil {
+		t.Fatalf("LoadYAMLFixtures() error = %v", err)
+	}
+	inserts := fake.queries("INSERT")
+	if len(inserts) != 1 {
+		t.Fatalf("inserts = %v, want one", inserts)
+	}
+	return inserts[0].Args
+}
+
+func TestOnMissingVarEnv(t *testing.T) {
+	t.Setenv("TESTKIT_TEST_HOST", "db.local")
+	const fixture = "servers:\n  - url: ${TESTKIT_TEST_HOST}:${TESTKIT_TEST_UNDEFINED}\n"
+
+	tests := []struct {
+		name string
+		mode MissingVarMode
+		want string
+	}{
+		{name: "empty", mode: MissingVarEmpty, want: "db.local:"},
+		{name: "keep", mode: MissingVarKeep, want: "db.local:${TESTKIT_TEST_UNDEFINED}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.ExpandEnv = true
+			config.OnMissingVar = tt.mode
+			fm, fake := newFakeManager(t, config)
+
+			values := insertedValues(t, fm, fake, writeFixture(t, "servers.yml", fixture))
+			if len(values) != 1 || values[0] != tt.want {
+				t.Errorf("inserted %v, want %q", values, tt.want)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		config := DefaultFixtureConfig()
+		config.ExpandEnv = true
+		fm, _ := newFakeManager(t, config)
+
+		err := fm.LoadYAMLFixtures(writeFixture(t, "servers.yml", fixture))
+		if err == nil {
+			t.Fatal("LoadYAMLFixtures() error = nil, want the undefined variable")
+		}
+		for _, want := range []string{"TESTKIT_TEST_UNDEFINED", "servers.yml", "column url"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not name %s", err, want)
+			}
+		}
+	})
+}
+
+func TestOnMissingVarTemplate(t *testing.T) {
+	const fixture = "tenants:\n  - name: \"{{.Name}}-{{.Region}}\"\n"
+
+	tests := []struct {
+		name string
+		mode MissingVarMode
+		want string
+	}{
+		{name: "empty", mode: MissingVarEmpty, want: "acme-"},
+		{name: "keep", mode: MissingVarKeep, want: "acme-<no value>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.OnMissingVar = tt.mode
+			fm, fake := newFakeManager(t, config)
+			fm.SetTemplateData(map[string]any{"Name": "acme"})
+
+			values := insertedValues(t, fm, fake, writeFixture(t, "tenants.yml", fixture))
+			if len(values) != 1 || values[0] != tt.want {
+				t.Errorf("inserted %v, want %q", values, tt.want)
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		fm, _ := newFakeManager(t, nil)
+		fm.SetTemplateData(map[string]any{"Name": "acme"})
+
+		err := fm.LoadYAMLFixtures(writeFixture(t, "tenants.yml", fixture))
+		if err == nil {
+			t.Fatal("LoadYAMLFixtures() error = nil, want the missing key")
+		}
+		for _, want := range []string{"Region", "tenants.yml"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q does not name %s", err, want)
+			}
+		}
+	})
+}
+
+func TestMissingVarEmptyOnlyEmptiesMissingKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "literal text", template: `<no value> {{.Name}}{{.Region}}`, want: "<no value> acme"},
+		{name: "literal data", template: `{{.Note}}-{{.Region}}`, want: "<no value>-"},
+		{name: "nested key", template: `{{.Name}}-{{.Region.Code}}`, want: "acme-"},
+		{name: "pipeline", template: `{{.Name | printf "%s!"}}{{.Region}}`, want: "acme!"},
+		{name: "inside blocks", template: `{{if .Name}}{{range seq 1}}{{$.Region}}{{end}}{{end}}x`, want: "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFixtureConfig()
+			config.OnMissingVar = MissingVarEmpty
+			fm, fake := newFakeManager(t, config)
+			fm.SetTemplateData(map[string]any{"Name": "acme", "Note": "<no value>"})
+
+			fixture := "tenants:\n  - name: \"" + tt.template + "\"\n"
+			values := insertedValues(t, fm, fake, writeFixture(t, "tenants.yml", fixture))
+			if len(values) != 1 || values[0] != tt.want {
+				t.Errorf("inserted %v, want %q", values, tt.want)
+			}
+		})
+	}
+}
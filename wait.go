@@ -0,0 +1,183 @@
+package testkit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/legrch/logger"
+)
+
+// RetryPolicy controls how WaitForHTTP polls an endpoint
+type RetryPolicy struct {
+	// Maximum number of attempts (defaults to 30), ignored when Timeout is set
+	MaxAttempts int
+	// Maximum time to keep polling, takes precedence over MaxAttempts when set
+	Timeout time.Duration
+	// Delay after the first failed attempt (defaults to 1s)
+	Interval time.Duration
+	// Factor applied to the delay after each further failed attempt (defaults to 1, a fixed Interval)
+	Multiplier float64
+	// Upper bound of the delay grown by Multiplier, 0 leaves it unbounded
+	MaxInterval time.Duration
+	// Status codes considered ready (defaults to 200)
+	AcceptStatusCodes []int
+	// Headers sent with every probe
+	Headers http.Header
+	// HTTP method used for probes (defaults to GET)
+	Method string
+}
+
+// UnexpectedStatusError is returned when a health probe answers with a status code that is not accepted
+type UnexpectedStatusError struct {
+	URL        string
+	StatusCode int
+}
+
+// Error implements error
+func (e *UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("health check %s returned status %d", e.URL, e.StatusCode)
+}
+
+// withDefaults returns a copy of the policy with unset fields defaulted
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 30
+	}
+	if p.Interval <= 0 {
+		p.Interval = time.Second
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 1
+	}
+	if len(p.AcceptStatusCodes) == 0 {
+		p.AcceptStatusCodes = []int{http.StatusOK}
+	}
+	if p.Method == "" {
+		p.Method = http.MethodGet
+	}
+	return p
+}
+
+// delay returns the wait after the given failed attempt, Interval grown by Multiplier and capped at MaxInterval
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := float64(p.Interval) * math.Pow(p.Multiplier, float64(attempt-1))
+	if p.MaxInterval > 0 && d > float64(p.MaxInterval) {
+		return p.MaxInterval
+	}
+	if d >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+// probeHTTP sends a single health probe and reports whether its status code is accepted
+// A rejected status code is reported as an *UnexpectedStatusError
+func probeHTTP(ctx context.Context, client *http.Client, url string, policy RetryPolicy) error {
+	// Create a context with timeout for the request
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel() // Always cancel the context to release resources
+
+	req, err := http.NewRequestWithContext(ctx, policy.Method, url, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range policy.Headers {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if !slices.Contains(policy.AcceptStatusCodes, resp.StatusCode) {
+		return &UnexpectedStatusError{URL: url, StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// WaitForHTTP polls url until it answers with an accepted status code
+// It gives up when the policy's attempts or timeout are exhausted, or when ctx is done
+func WaitForHTTP(ctx context.Context, client *http.Client, url string, policy RetryPolicy) error {
+	policy = policy.withDefaults()
+	return poll(ctx, policy, []any{"url", url}, func(ctx context.Context) error {
+		return probeHTTP(ctx, client, url, policy)
+	})
+}
+
+// poll calls probe until it succeeds, following the attempts, timeout and backoff of the policy
+// target holds the log attributes identifying what is probed
+func poll(ctx context.Context, policy RetryPolicy, target []any, probe func(ctx context.Context) error) error {
+	start := time.Now()
+	var deadline time.Time
+	if policy.Timeout > 0 {
+		deadline = start.Add(policy.Timeout)
+	}
+
+	for attempt := 1; ; attempt++ {
+		probeStart := time.Now()
+		err := probe(ctx)
+
+		attrs := append(slices.Clip(target),
+			"attempt", attempt,
+			"probe_duration", time.Since(probeStart),
+			"elapsed", time.Since(start),
+		)
+		if deadline.IsZero() {
+			attrs = append(attrs, "max_attempts", policy.MaxAttempts)
+		}
+		delay := policy.delay(attempt)
+		attrs = append(attrs, "next_delay", delay)
+
+		var statusErr *UnexpectedStatusError
+		switch {
+		case err == nil:
+			logger.Info("Server is ready", append(slices.Clip(target), "attempts", attempt, "total_wait", time.Since(start))...)
+			return nil
+		case errors.As(err, &statusErr):
+			logger.Info("Server is not ready", append(attrs, "status", statusErr.StatusCode)...)
+		case ctx.Err() != nil:
+			return fmt.Errorf("stopped waiting for server: %w", ctx.Err())
+		default:
+			logger.Info("Server is not ready", append(attrs, "error", err)...)
+		}
+
+		if deadline.IsZero() {
+			if attempt >= policy.MaxAttempts {
+				logger.Warn("Server did not become ready", append(slices.Clip(target), "attempts", attempt, "total_wait", time.Since(start))...)
+				return fmt.Errorf("server did not respond after %d attempts", policy.MaxAttempts)
+			}
+		} else if time.Now().Add(delay).After(deadline) {
+			logger.Warn("Server did not become ready", append(slices.Clip(target), "attempts", attempt, "total_wait", time.Since(start))...)
+			return fmt.Errorf("server did not respond within %s", time.Since(start).Round(time.Millisecond))
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for server after %s: %w", time.Since(start).Round(time.Millisecond), ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// WaitForFunc polls ready until it returns nil, with the same attempts, timeout and backoff as WaitForHTTP
+// Each call gets a context limited to DefaultTimeout
+func WaitForFunc(ctx context.Context, ready func(ctx context.Context) error, policy RetryPolicy) error {
+	policy = policy.withDefaults()
+	return poll(ctx, policy, []any{"probe", "ReadinessFunc"}, func(ctx context.Context) error {
+		return probeFunc(ctx, ready)
+	})
+}
+
+// probeFunc calls a readiness function once with a context limited to DefaultTimeout
+func probeFunc(ctx context.Context, ready func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	return ready(ctx)
+}
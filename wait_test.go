@@ -0,0 +1,236 @@
+package testkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/legrch/logger"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		want   []time.Duration
+	}{
+		{
+			name:   "fixed interval",
+			policy: RetryPolicy{Interval: 100 * time.Millisecond},
+			want:   []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+		},
+		{
+			name:   "exponential",
+			policy: RetryPolicy{Interval: 100 * time.Millisecond, Multiplier: 2},
+			want:   []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond},
+		},
+		{
+			name:   "capped",
+			policy: RetryPolicy{Interval: 100 * time.Millisecond, Multiplier: 3, MaxInterval: 500 * time.Millisecond},
+			want:   []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name:   "defaults",
+			policy: RetryPolicy{Multiplier: 0.5},
+			want:   []time.Duration{time.Second, time.Second},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy.withDefaults()
+			for i, want := range tt.want {
+				if got := policy.delay(i + 1); got != want {
+					t.Errorf("delay(%d) = %v, want %v", i+1, got, want)
+				}
+			}
+		})
+	}
+
+	// Unbounded growth saturates instead of overflowing
+	policy := RetryPolicy{Interval: time.Hour, Multiplier: 10}.withDefaults()
+	if got := policy.delay(100); got <= 0 {
+		t.Errorf("delay(100) = %v, want a saturated positive delay", got)
+	}
+}
+
+func TestWaitForFuncGivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    RetryPolicy
+		wantCalls int32
+		wantErr   string
+	}{
+		{
+			name:      "attempts",
+			policy:    RetryPolicy{MaxAttempts: 4, Interval: time.Millisecond, Multiplier: 2},
+			wantCalls: 4,
+			wantErr:   "server did not respond after 4 attempts",
+		},
+		{
+			// Waiting 20ms and 40ms stays within the timeout, the next 80ms would pass it
+			name:      "timeout",
+			policy:    RetryPolicy{Timeout: 100 * time.Millisecond, Interval: 20 * time.Millisecond, Multiplier: 2},
+			wantCalls: 3,
+			wantErr:   "server did not respond within",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			err := WaitForFunc(context.Background(), func(context.Context) error {
+				calls.Add(1)
+				return errors.New("not yet")
+			}, tt.policy)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("WaitForFunc() error = %v, want %q", err, tt.wantErr)
+			}
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWaitForFuncStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	err := WaitForFunc(ctx, func(context.Context) error {
+		cancel()
+		return errors.New("not yet")
+	}, RetryPolicy{MaxAttempts: 100, Interval: time.Hour})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("WaitForFunc() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestWaitForHTTP(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodHead || req.Header.Get("X-Probe") != "testkit" {
+			t.Errorf("probe %s with X-Probe %q, want HEAD with the policy headers", req.Method, req.Header.Get("X-Probe"))
+		}
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	policy := RetryPolicy{
+		MaxAttempts:       5,
+		Interval:          time.Millisecond,
+		AcceptStatusCodes: []int{http.StatusNoContent},
+		Headers:           http.Header{"X-Probe": {"testkit"}},
+		Method:            http.MethodHead,
+	}
+	if err := WaitForHTTP(context.Background(), server.Client(), server.URL, policy); err != nil {
+		t.Fatalf("WaitForHTTP() error = %v", err)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+
+	// 200 is not accepted when other status codes are configured
+	err := probeHTTP(context.Background(), server.Client(), server.URL, RetryPolicy{
+		AcceptStatusCodes: []int{http.StatusOK},
+		Headers:           policy.Headers,
+		Method:            http.MethodHead,
+	})
+	var statusErr *UnexpectedStatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNoContent || statusErr.URL != server.URL {
+		t.Errorf("probeHTTP() error = %v, want an UnexpectedStatusError with status 204", err)
+	}
+}
+
+// captureLogs routes the global logger to a JSON buffer until the test finishes and returns the decoded records
+func captureLogs(t *testing.T) func() []map[string]any {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := logger.Default()
+	logger.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { logger.SetDefault(previous) })
+
+	return func() []map[string]any {
+		var records []map[string]any
+		decoder := json.NewDecoder(&buf)
+		for decoder.More() {
+			var record map[string]any
+			if err := decoder.Decode(&record); err != nil {
+				t.Fatalf("failed to decode log record: %v", err)
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+}
+
+func TestWaitForHTTPLogsAttempts(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	logs := captureLogs(t)
+	policy := RetryPolicy{MaxAttempts: 3, Interval: time.Millisecond}
+	if err := WaitForHTTP(context.Background(), server.Client(), server.URL, policy); err != nil {
+		t.Fatalf("WaitForHTTP() error = %v", err)
+	}
+
+	records := logs()
+	if len(records) != 2 {
+		t.Fatalf("log records = %v, want a failed attempt and the ready message", records)
+	}
+	failed, ready := records[0], records[1]
+	if failed["msg"] != "Server is not ready" || failed["url"] != server.URL || failed["attempt"] != 1.0 ||
+		failed["status"] != 503.0 || failed["max_attempts"] != 3.0 {
+		t.Errorf("failed attempt = %v, want the attempt, status and max attempts of %s", failed, server.URL)
+	}
+	for _, key := range []string{"probe_duration", "elapsed", "next_delay"} {
+		if _, ok := failed[key]; !ok {
+			t.Errorf("failed attempt = %v, want %s", failed, key)
+		}
+	}
+	if ready["msg"] != "Server is ready" || ready["attempts"] != 2.0 || ready["total_wait"] == nil {
+		t.Errorf("ready message = %v, want 2 attempts and the total wait", ready)
+	}
+}
+
+func TestWaitForFuncLogsGivingUp(t *testing.T) {
+	logs := captureLogs(t)
+	policy := RetryPolicy{MaxAttempts: 2, Interval: time.Millisecond}
+	refused := func(context.Context) error { return errors.New("connection refused") }
+	err := WaitForFunc(context.Background(), refused, policy)
+	if err == nil {
+		t.Fatal("WaitForFunc() error = nil, want the attempts exhausted")
+	}
+
+	records := logs()
+	if len(records) != 3 {
+		t.Fatalf("log records = %v, want two failed attempts and the warning", records)
+	}
+	for i, record := range records[:2] {
+		attempt := float64(i + 1)
+		if record["probe"] != "ReadinessFunc" || record["error"] != "connection refused" || record["attempt"] != attempt {
+			t.Errorf("attempt %d = %v, want the probe error", i+1, record)
+		}
+	}
+	last := records[2]
+	if last["level"] != "WARN" || last["msg"] != "Server did not become ready" || last["attempts"] != 2.0 {
+		t.Errorf("last record = %v, want a warning after 2 attempts", last)
+	}
+}